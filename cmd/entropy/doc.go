@@ -15,22 +15,53 @@ The output is a csv file for plotting with some other program. It has a header l
 
 
 Usage:
-	entropy [flags] [input]
+	entropy [flags] [input [output]]
+
+If there is no input file, or it is "-", sequences are read from standard
+input, so one can use it in a pipe, "cat aln.fa | entropy".
 
 The flags are:
+	-a alphabet
+		protein, dna or rna. Without this, the type is guessed from the symbols. With an alphabet, lower case is counted as upper case, "." and "~" as gaps and, for proteins, selenocysteine (U) as cysteine and pyrrolysine (O) as lysine. The base of the logarithms is the size of the alphabet.
 	-c chimera_attribute_file
 		Write conservation data to a file in the format of an attribute file that chimera can read and use for coloring a structure.
 	-f oFfset
 		When creating output for plotting, we assume the first residue is numbered 1. This allows one to add an offset to be added or subtracted (if negative) to each number.
 	-g
 		Treat gaps as a valid character
+	-gapchars chars
+		Characters which are also gaps, such as ".~" from other aligners. They are read as "-". Without this (or -a), only "-" is a gap.
+	-id threshold
+		Weight each sequence by 1/n, where n is the number of sequences (itself included) at least threshold identical to it, as in plmc and EVcouplings. 0.8 is usual. The effective number of sequences, Neff, is written to standard error and a "neff" column gives the weighted number of sequences with a residue at each site. It cannot be used with -w or -stream.
+	-idnogaps
+		With -id, identity is only over the sites where both sequences have a residue. Without it, a gap is a symbol like any other, so two gaps are identical. It is an error without -id.
+	-informat format
+		Read the input as this format (fasta, a2m, clustal, msf, nexus, phylip, stockholm, fastq). Without it, the format is guessed from the start of the file.
+	-m
+		The input is in A2M or A3M format (HHblits, jackhmmer). Lower case letters and "." are insert states. Only the match state columns are used, so output is numbered by match state.
 	-n base
-		Set the base for logarithms and override the guess. 20 for protein. 4 for DNA.
+		Set the base for logarithms and override the guess. 20 for protein. 4 for DNA. It must be at least the number of different symbols in the alignment, not counting gaps or ambiguity codes (X and N, or those of the alphabet given with -a).
+	-nworker N
+		Read fasta and count symbols with N goroutines. This helps with deep alignments on machines with several processors. Reading in parallel means the whole file is taken into memory and cut into pieces at lines starting with ">", which are parsed by the same rules as with one goroutine. Counts are integers added up at the end. The output is the same as with one.
 	-o Outfilename
 		Output file name, instead of standard output
+	-prior name
+		Add pseudocounts before working out frequencies, so a few sequences do not give absurdly low entropies. "uniform" adds one to the count of every symbol in the alphabet. "background" adds as many pseudocounts as there are symbols, shared out by the BLOSUM62 background frequencies for proteins or equally otherwise. "blosum62" adds ten, shared out by what BLOSUM62 says the residues in the column turn into, as in PSI-BLAST. Follow any of them with a colon and a number, like "uniform:0.5", to change the amount. Anything else is read as a Dirichlet mixture in the .9comp format of the SAM package. "blosum62" and Dirichlet mixtures are only for proteins, and a mixture must have the twenty amino acids. Gaps never get pseudocounts. Compatibility with the reference is estimated the same way.
+	-q cutoff
+		For fastq input, bases with a Phred quality below cutoff are not counted.
+	-qprob
+		For fastq input, each base counts as the probability that it was called correctly, 1 - 10^(-q/10), rather than one. Gaps always count one.
 	-r reference
 		Specify a reference sequence by give a string which will be searched
 		for in the comment lines of the sequences
+	-stream
+		Count each sequence as it is read and throw it away, so the alignment does not have to fit in memory. Only the counts at each site (and the reference sequence) are kept. The output is the same. It cannot be used with -m, -q or -qprob, and -nworker does not speed up reading.
+	-tgap
+		Treat leading and trailing gaps, usually from fragments, separately from internal gaps. They are weighted by -tgapwt in the gap fraction and entropy, and the fraction of sequences with a terminal gap at each site is written as an extra column.
+	-tgapwt weight
+		With -tgap, how much a terminal gap counts compared to an internal one. 0 (the default) ignores them, so a site is judged by the sequences which reach it. 1 counts them like any other gap.
+	-w
+		Weight each sequence with the position-based weights of Henikoff and Henikoff, so a crowd of near-identical sequences counts for less than the same number of different ones. Entropy, gap fractions and compatibility all use the weighted counts. It needs the whole alignment, so it cannot be used with -stream.
 
 If you have a reference sequence, the compatibility of each base/residue will be calculated and printed out.
 
@@ -40,6 +71,6 @@ Output is written to the standard output, which you probably do not want. Catch
 The format is .csv with quoted heading for the columns. It can be eaten with read.csv in R or imported straight into excel. Gnuplot also knows what to do with it.
 
 TODO
-One could add a mapping of input residues to output, so, for example, selenomethionine becomes methionine.
+The remapping of residues (U to C, O to K) is fixed for the built in alphabets. One could add a flag to give more, such as a modified residue code to its parent.
 */
 package main
@@ -27,12 +27,26 @@ func main() {
 	var flags entropy.CmdFlag
 	var infile, outfile string
 
+	flag.StringVar(&flags.Alphabet, "a", "", "alphabet, protein, dna or rna, guessed by default")
 	flag.StringVar(&flags.Chimera, "c", "", "filename to write chimera format to")
 	flag.IntVar(&flags.Offset, "f", 0, "offset for numbering output, renumbering sites")
 	flag.BoolVar(&flags.GapsAreChar, "g", false, "gap is a valid symbol")
+	flag.StringVar(&flags.GapChars, "gapchars", "", "more gap characters, like \".~\"")
+	flag.Float64Var(&flags.IdThresh, "id", 0, "weight sequences by 1/neighbours at this identity, like 0.8, 0 for off")
+	flag.BoolVar(&flags.IdNoGaps, "idnogaps", false, "with -id, only compare sites where both sequences have residues")
+	flag.StringVar(&flags.InFormat, "informat", "", "input format, guessed by default")
+	flag.BoolVar(&flags.MatchOnly, "m", false, "input is A2M/A3M, only use match states")
 	flag.IntVar(&flags.NSym, "n", -1, "num symbols, guessed by default, 4 for DNA")
+	flag.IntVar(&flags.NWorker, "nworker", 1, "goroutines for reading and counting")
+	flag.StringVar(&flags.Prior, "prior", "", "pseudocounts, uniform, background or blosum62 (each with optional :weight) or a .9comp file")
+	flag.IntVar(&flags.QualCutoff, "q", 0, "fastq input, ignore bases with quality below this")
+	flag.BoolVar(&flags.QualProb, "qprob", false, "fastq input, weight bases by probability they are right")
 	flag.StringVar(&flags.RefSeq, "r", "", "reference sequence, check compatibility")
+	flag.BoolVar(&flags.Stream, "stream", false, "count sequences as they are read, without keeping them")
 	flag.BoolVar(&flags.Time, "t", false, "print out timing information")
+	flag.BoolVar(&flags.TermGaps, "tgap", false, "weight leading and trailing gaps separately, report their fraction")
+	flag.Float64Var(&flags.TermGapWt, "tgapwt", 0, "with -tgap, weight of terminal gaps, 0 ignores them, 1 is like other gaps")
+	flag.BoolVar(&flags.Weight, "w", false, "weight sequences with Henikoff position-based weights")
 	flag.Usage = usage
 	flag.Parse()
 	if flag.NArg() > 0 {
@@ -41,12 +55,6 @@ func main() {
 			outfile = flag.Arg(1)
 		}
 	}
-	if flag.NArg() == 0 {
-		fmt.Fprintln (os.Stderr, "Too few arguments")
-		usage()
-		os.Exit(ExitFailure)
-	}
-
 	if err := entropy.Mymain(&flags, infile, outfile); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(ExitFailure)
@@ -0,0 +1,28 @@
+// 17 Oct 2026
+/*
+
+faidx pulls sequences, or pieces of sequences, out of a big fasta file
+without reading the whole file. It uses an index, file.fa.fai, in the
+same format as samtools faidx, so an index made by either program can be
+used by the other.
+
+Usage:
+	faidx [flags] file.fa [region ...]
+
+Given only the fasta file, faidx builds the index and writes it to
+file.fa.fai. Given regions, it writes them in fasta format. If there is
+no index, it is built first.
+
+A region is a sequence name (the first word of the comment), or
+name:from-to or name:from. Residues are numbered from 1 and "to" is
+included, as in samtools. For example
+	faidx big.fa sp|P69905|HBA_HUMAN chr1:1001-2000
+
+The flags are:
+	-o Outfilename
+		Output file name, instead of standard output
+
+All the lines of a sequence, except the last, must be the same length
+or the file cannot be indexed. Compressed files cannot be indexed.
+*/
+package main
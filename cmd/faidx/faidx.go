@@ -0,0 +1,36 @@
+// 17 Oct 2026
+// Pull named sequences or regions out of a fasta file using an index.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/andrew-torda/seq_compat/pkg/faidx"
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// usage
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:", path.Base(os.Args[0]), "[opts] file.fa [region ...]")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var outfile string
+	flag.StringVar(&outfile, "o", "", "output file name, default stdout")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(ExitUsageError)
+	}
+	if err := faidx.Mymain(flag.Arg(0), flag.Args()[1:], outfile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitFailure)
+	}
+	os.Exit(ExitSuccess)
+}
@@ -8,20 +8,63 @@ Results are written in csv format.
 Usage:
  kl [options] file1.fa file2.fa
 
+One of the files may be "-", which means standard input.
+
 Flags:
   -f N
     	On output, we number each site starting from 1, but we can add
     	an offset of N to each value. It can be negative.
   -g	Gaps are a valid symbol. Without this option, gaps are ignored
   		in calculations.
+  -gapchars chars
+    	Characters which are also gaps, such as ".~" from other
+    	aligners. Without this, only "-" is a gap.
+  -id threshold
+    	Weight each sequence by 1/n, where n is the number of sequences
+    	in its file at least threshold (like 0.8) identical to it. It
+    	cannot be used with -w or -stream.
+  -idnogaps
+    	With -id, only compare sites where both sequences have a
+    	residue. Without it, two gaps count as identical. It is an
+    	error without -id.
+  -informat format
+    	Read both files as this format (fasta, a2m, clustal, msf, nexus,
+    	phylip, stockholm, fastq). Without it, the format of each file
+    	is guessed from its start.
   -n N
     	Treat the sequences as having N symbols. Without this, the
     	code will try to guess if we have nucleotides (4 symbols) or
-    	proteins (20 symbols).
+    	proteins (20 symbols). N is the base for logarithms. It must be
+    	at least the number of different symbols in the two files, not
+    	counting gaps, X and N.
+  -nworker N
+    	Read and count each file with N goroutines. The two files
+    	are already read at the same time. For parallel reading,
+    	each file is taken into memory and cut into pieces at lines
+    	starting with ">", which are parsed by the same rules as
+    	with one goroutine. Results are the same as with one.
 
   -o filename
     	Write output to filename. If not give, numbers are written to
     	standard output
+  -prior name
+    	Add pseudocounts to the frequencies in both files. "uniform"
+    	adds one to every symbol, "background" adds as many as there
+    	are symbols, shared by the BLOSUM62 background frequencies for
+    	proteins, and "blosum62" adds ten, shared by what BLOSUM62 says
+    	the residues in the column turn into (PSI-BLAST). A colon and
+    	number, like "blosum62:5", changes the amount. Anything else is
+    	read as a Dirichlet mixture in .9comp format. "blosum62" and
+    	mixtures are only for proteins, with the twenty amino acids.
+  -stream
+    	Count each sequence as it is read and throw it away, so a
+    	file does not have to fit in memory. Only the counts at each
+    	site are kept. Results are the same. -nworker does not speed
+    	up reading.
+  -w
+    	Weight the sequences in each file with the position-based
+    	weights of Henikoff and Henikoff, so near-identical sequences
+    	count for less. It cannot be used with -stream.
 
 One should probably pick a filename that ends in  ".csv". This is not
 enforced.
@@ -31,6 +74,7 @@ The columns in the output have labels which should be read by any plotting progr
 The two input files must be from multiple sequence alignments. The sequences within each file have to be of the same length, including gaps. The sequences in the two files have to be of the same length.
 
 The Kullbach-Leibler distance, (kl= sum (p_a * log (p_a/q_a))) is fundamentally asymmetric, so we calculate the results using one file as the p distribution and also as the q distribution. If p_a or q_a is zero for some amino acid/nucleotide type, there is no correct behaviour. We take a pessimistic view. If you have N sequences and symbol "a" does not appear in one distribution, we take its frequency to be 1/(N+1). That is, if you have a 100 sequences and you do not see type X, we say the probability of X occurring is 1/101. The rationale is that this is the best estimate. If you have 10000 sequences and do not see symbol X, you can say that its probability is 1/10001. If you have 10 sequences, you cannot be so sure, so its probability becomes 1/11.
+With -prior, this is only needed for symbols the prior does not know about, like X. Everything else already has a pseudocount, which is a better estimate than 1/(N+1) when there are only a few sequences.
 
 MISSING: to do
 I think it might be helpful if one could name a reference seqeunce and print it out in a column next to the numerical outputs.
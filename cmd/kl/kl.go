@@ -31,8 +31,16 @@ func main() {
 	outfile := "-"
 	flag.IntVar(&flags.Offset, "f", 0, "offset for numbering output")
 	flag.BoolVar(&flags.GapsAreChar, "g", false, "gap is a valid symbol")
+	flag.StringVar(&flags.GapChars, "gapchars", "", "more gap characters, like \".~\"")
+	flag.Float64Var(&flags.IdThresh, "id", 0, "weight sequences by 1/neighbours at this identity, like 0.8, 0 for off")
+	flag.BoolVar(&flags.IdNoGaps, "idnogaps", false, "with -id, only compare sites where both sequences have residues")
+	flag.StringVar(&flags.InFormat, "informat", "", "input format, guessed by default")
 	flag.IntVar(&flags.NSym, "n", -1, "num symbols, guessed by default, 4 for DNA")
+	flag.IntVar(&flags.NWorker, "nworker", 1, "goroutines for reading and counting each file")
 	flag.StringVar(&outfile, "o", "", "output file name, default stdout")
+	flag.StringVar(&flags.Prior, "prior", "", "pseudocounts, uniform, background or blosum62 (each with optional :weight) or a .9comp file")
+	flag.BoolVar(&flags.Stream, "stream", false, "count sequences as they are read, without keeping them")
+	flag.BoolVar(&flags.Weight, "w", false, "weight sequences with Henikoff position-based weights")
 	flag.Parse()
 
 	seqf1 := flag.Arg(0)
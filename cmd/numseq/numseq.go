@@ -1,7 +1,8 @@
 // 3 Aug 2020
 
 // Open a file and count the number of ">" characters. This might be
-// The number of sequences.
+// The number of sequences. Without a filename, or given "-", read
+// standard input.
 
 package main
 
@@ -14,15 +15,18 @@ import (
 )
 
 func usage () {
-	fmt.Fprintln (os.Stderr, "usage:", os.Args[0], "filename")
+	fmt.Fprintln (os.Stderr, "usage:", os.Args[0], "[filename]")
 }
 
 func main() {
-	if len (os.Args) < 2 {
+	if len (os.Args) > 2 {
 		usage()
 		os.Exit (ExitUsageError)
 	}
-	fname := os.Args[1]
+	fname := "-"
+	if len (os.Args) == 2 {
+		fname = os.Args[1]
+	}
 	var err error
 	var nOccur int
 	if nOccur, err = numseq.FromFile(fname); err != nil {
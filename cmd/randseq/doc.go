@@ -15,6 +15,10 @@ Flags:
 		for programs like entropy.
 	-r
 		random number seed
+	-outformat format
+		write the sequences in this format (fasta, clustal, msf,
+		nexus, phylip). The default is fasta. In these, each
+		sequence is named by its number, so the names are unique.
 
 We are most interested in benchmarking and parsing, so the content is not so important.
 The only question that comes up is white space and gaps.
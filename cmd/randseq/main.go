@@ -20,6 +20,7 @@ func main() {
 	f.Usage = func () {fmt.Println(uStr); f.PrintDefaults()}
 	f.BoolVar(&args.NoGap, "g", false, "do not put gaps in sequences")
 	f.BoolVar(&args.MkErr, "e", false, "provoke errors")
+	f.StringVar(&args.Format, "outformat", "", "output format, default fasta")
 	f.Int64Var(&args.Iseed, "r", iseed, "random number seed")
 	f.BoolVar (&args.NoSpace, "s", false, "do not put spaces in sequences")
 	if err := f.Parse(os.Args[1:]); err != nil {
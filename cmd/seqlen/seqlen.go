@@ -1,6 +1,6 @@
 // 25 may 2025
 // seqlen visits a fasta file and counts the length of each sequence
-// after removing gaps.
+// after removing gaps. An input file of "-" means standard input.
 
 package main
 
@@ -22,6 +22,9 @@ func main () {
 	var cmdArgs seqlen.CmdArgs
 	flag.BoolVar (&cmdArgs.IgnrSeqLen, "i", false, "ignore sequence lengths not being consistent")
 	flag.StringVar(&cmdArgs.OutSeqFname, "s", "", "Write cleaned sequences to")
+	flag.StringVar(&cmdArgs.InFormat, "informat", "", "input format, guessed by default")
+	flag.StringVar(&cmdArgs.OutFormat, "outformat", "", "format for -s output, default fasta")
+	flag.StringVar(&cmdArgs.Template, "t", "", "regexp for comments, with groups like (?P<accession>...) (?P<organism>...)")
 	flag.Usage = func() {
         fmt.Fprintf(os.Stderr, "Usage: %s [options] input output\n\n", os.Args[0])
         flag.PrintDefaults()
@@ -0,0 +1,38 @@
+// 17 Oct 2026
+/*
+
+seqlint checks an alignment before it is used for conservation
+calculations. Most programs stop at the first problem. seqlint reports
+all of them in one go.
+
+Usage:
+	seqlint [flags] [input]
+
+If there is no input file, or it is "-", sequences are read from
+standard input. Any of the formats the other programs read can be used.
+
+It looks for
+	sequences which are not the same length as most of the others
+	symbols which are not letters, gaps or "*", including non-ascii bytes
+	sequences with the same id (accession)
+	identical sequences
+	empty sequences, or sequences with only gaps
+	columns which are gaps in every sequence
+	more than one gap character ("-", "." and "~")
+	a guess of the sequence type which looks wrong, such as DNA with
+	enough ambiguity codes that it looks like protein
+
+Sequences and columns are numbered from 1.
+
+The flags are:
+	-informat format
+		Read the input as this format. Without it, the format is guessed.
+	-j
+		Write the report as JSON instead of text.
+	-o Outfilename
+		Output file name, instead of standard output
+
+The exit status is 0 if nothing was found and 1 if there were problems
+or the file could not be read.
+*/
+package main
@@ -0,0 +1,43 @@
+// 17 Oct 2026
+// Check an alignment for problems before using it.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+	"github.com/andrew-torda/seq_compat/pkg/seqlint"
+)
+
+// usage
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage:", path.Base(os.Args[0]), "[opts] [input]")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var cmdArgs seqlint.CmdArgs
+	flag.StringVar(&cmdArgs.InFormat, "informat", "", "input format, guessed by default")
+	flag.BoolVar(&cmdArgs.JSON, "j", false, "write the report as JSON")
+	flag.StringVar(&cmdArgs.OutFname, "o", "", "output file name, default stdout")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() > 1 {
+		usage()
+		os.Exit(ExitUsageError)
+	}
+	cmdArgs.InSeqFname = flag.Arg(0)
+	n, err := seqlint.Mymain(cmdArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitFailure)
+	}
+	if n > 0 {
+		os.Exit(ExitFailure)
+	}
+	os.Exit(ExitSuccess)
+}
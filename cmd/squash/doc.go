@@ -9,11 +9,20 @@ From the multiple sequence alignment remove any columns which
 correspond to a gap in the specified sequence.
 
 Usage:
-	squash name [input] [output]
+	squash [flags] name [input] [output]
 
 
 If no output file is given, stdout will be used.
-If no input file is given, stdin will be used.
+If no input file is given, or it is "-", stdin will be used.
+
+The flags are:
+	-informat format
+		Read the input as this format (fasta, a2m, clustal, msf,
+		nexus, phylip, stockholm, fastq). Without it, the format is
+		guessed from the start of the file.
+	-outformat format
+		Write the squashed alignment in this format (fasta, clustal,
+		msf, nexus, phylip). The default is fasta.
 
 The name argument is tricky. It is a string, so it will have to be quoted
 on the command line.
@@ -27,6 +36,10 @@ and there is another that starts
  > blah blah foo foo [monkey]
 and you search for "blah blah", it will return the first sequence it
 finds, even if you want the monkey sequence.
+Before looking inside comments, it looks for a sequence whose accession
+or UniProt entry name is exactly the string, so "P69905" or "HBA_HUMAN"
+will find
+ > sp|P69905|HBA_HUMAN Hemoglobin subunit alpha OS=Homo sapiens
 There is more.
 If the string starts and ends with an integer like "1" it will take
 this as the number of the sequence to be removed.
@@ -4,10 +4,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path"
 
+	"github.com/andrew-torda/seq_compat/pkg/seq"
 	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
 	"github.com/andrew-torda/seq_compat/pkg/squash"
 )
@@ -15,23 +17,29 @@ import (
 // usage
 func usage() {
 	name := path.Base(os.Args[0])
-	fmt.Println("usage:", name, "sequence_string [inputfile [outputfile]]")
+	fmt.Fprintln(os.Stderr, "usage:", name, "[opts] sequence_string [inputfile [outputfile]]")
+	flag.PrintDefaults()
 	os.Exit(ExitFailure)
 }
 
 func main() {
 	var seqstring, infile, outfile string
-	nArg := len(os.Args)
-	if nArg <= 1 {
+	var s_opts seq.Options
+	flag.StringVar(&s_opts.InFormat, "informat", "", "input format, guessed by default")
+	flag.StringVar(&s_opts.OutFormat, "outformat", "", "output format, default fasta")
+	flag.Usage = usage
+	flag.Parse()
+	nArg := flag.NArg()
+	if nArg < 1 {
 		fmt.Fprintln(os.Stderr, "require at least one command line argument")
 		usage()
 	}
-	seqstring = os.Args[1]
-	if nArg > 2 {
-		infile = os.Args[2]
+	seqstring = flag.Arg(0)
+	if nArg > 1 {
+		infile = flag.Arg(1)
 	}
-	if nArg > 3 {
-		outfile = os.Args[3]
+	if nArg > 2 {
+		outfile = flag.Arg(2)
 	}
-	os.Exit(squash.MyMain(seqstring, infile, outfile))
+	os.Exit(squash.MyMain(seqstring, infile, outfile, &s_opts))
 }
@@ -7,8 +7,11 @@ import (
 	"github.com/andrew-torda/seq_compat/pkg/seq"
 	"github.com/andrew-torda/seq_compat/pkg/seq/common"
 
+	"fmt"
 	"math"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -116,3 +119,215 @@ func TestChimera(t *testing.T) {
 		t.Fatal("bust with chimera file", err)
 	}
 }
+
+// TestStream runs with and without -stream, with a reference sequence
+// and terminal gaps. The output files must be the same.
+func TestStream(t *testing.T) {
+	fname, err := common.WrtTemp(seqstring3)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	var out [2][]byte
+	for i, stream := range []bool{false, true} {
+		tmpout, err := os.CreateTemp(".", "del_me")
+		if err != nil {
+			t.Fatal("Fail making test file", err.Error())
+		}
+		tmpout.Close()
+		defer os.Remove(tmpout.Name())
+		flags := CmdFlag{RefSeq: "s2", TermGaps: true, TermGapWt: 0.5, Stream: stream}
+		if err := Mymain(&flags, fname, tmpout.Name()); err != nil {
+			t.Fatal("stream", stream, err)
+		}
+		if out[i], err = os.ReadFile(tmpout.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out[0]) != string(out[1]) {
+		t.Fatalf("streaming gave\n%s\nwanted\n%s", out[1], out[0])
+	}
+	flags := CmdFlag{Stream: true, MatchOnly: true}
+	if err := Mymain(&flags, fname, ""); err == nil {
+		t.Fatal("streaming should not work with match states")
+	}
+}
+
+// TestNWorker checks -nworker, which reads and counts in parallel,
+// gives the same output as one goroutine. The fasta has a ">" in the
+// middle of a line, which starts a new sequence either way.
+func TestNWorker(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&sb, "> s%d\nAC-%c\nDE>s%dx\nAC  DE-G\n", i, "ACDEFG"[i%6], i)
+	}
+	fname, err := common.WrtTemp(sb.String())
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	var out [2][]byte
+	for i, nworker := range []int{1, 4} {
+		tmpout, err := os.CreateTemp(".", "del_me")
+		if err != nil {
+			t.Fatal("Fail making test file", err.Error())
+		}
+		tmpout.Close()
+		defer os.Remove(tmpout.Name())
+		flags := CmdFlag{RefSeq: "s7", NWorker: nworker}
+		if err := Mymain(&flags, fname, tmpout.Name()); err != nil {
+			t.Fatal("nworker", nworker, err)
+		}
+		if out[i], err = os.ReadFile(tmpout.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out[0]) != string(out[1]) {
+		t.Fatalf("4 workers gave\n%s\nwanted\n%s", out[1], out[0])
+	}
+}
+
+// TestEmptyColumn has a first column of nothing but terminal gaps,
+// which are ignored, so no residues are there at all.
+func TestEmptyColumn(t *testing.T) {
+	fname, err := common.WrtTemp("> s1\n-AC\n> s2\n-AC\n")
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	outname := fname + ".csv"
+	defer os.Remove(outname)
+	flags := CmdFlag{TermGaps: true}
+	if err := Mymain(&flags, fname, outname); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(outname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := strings.Split(strings.Split(string(out), "\n")[1], ",")
+	if e, _ := strconv.ParseFloat(f[1], 32); e != 0 || f[2] != "0.00" || f[3] != "1.00" {
+		t.Fatal("wanted no entropy and no residues, got", f)
+	}
+}
+
+// TestPrior checks streaming and reading give the same entropies with
+// pseudocounts, and that the pseudocounts make a difference.
+func TestPrior(t *testing.T) {
+	fname, err := common.WrtTemp(seqstring3)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	var out [3][]byte
+	for i, f := range []CmdFlag{{}, {Prior: "blosum62"}, {Prior: "blosum62", Stream: true}} {
+		tmpout, err := os.CreateTemp(".", "del_me")
+		if err != nil {
+			t.Fatal("Fail making test file", err.Error())
+		}
+		tmpout.Close()
+		defer os.Remove(tmpout.Name())
+		f.RefSeq = "s2"
+		if err := Mymain(&f, fname, tmpout.Name()); err != nil {
+			t.Fatal("prior", f.Prior, "stream", f.Stream, err)
+		}
+		if out[i], err = os.ReadFile(tmpout.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out[0]) == string(out[1]) {
+		t.Fatal("prior made no difference")
+	}
+	if string(out[1]) != string(out[2]) {
+		t.Fatalf("streaming gave\n%s\nwanted\n%s", out[2], out[1])
+	}
+	flags := CmdFlag{Prior: "nonsense"}
+	if err := Mymain(&flags, fname, os.DevNull); err == nil {
+		t.Fatal("nonsense prior should fail")
+	}
+}
+
+// TestIdThresh has each sequence 60 % identical to the ones next to it
+// in the file, so the ends count 1/2, the middle two 1/3 and Neff is
+// 5/3 in the first column. A silly threshold is refused, as is
+// -idnogaps without a threshold.
+func TestIdThresh(t *testing.T) {
+	fname, err := common.WrtTemp(seqstring3)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	tmpout, err := os.CreateTemp(".", "del_me")
+	if err != nil {
+		t.Fatal("Fail making test file", err.Error())
+	}
+	tmpout.Close()
+	defer os.Remove(tmpout.Name())
+	flags := CmdFlag{IdThresh: 0.6}
+	if err := Mymain(&flags, fname, tmpout.Name()); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(tmpout.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(b), "\n")
+	if !strings.HasSuffix(lines[0], `"neff"`) || !strings.HasSuffix(lines[1], ",1.7") {
+		t.Fatal("neff column wrong\n", string(b))
+	}
+	flags.IdThresh = 2
+	if err := Mymain(&flags, fname, os.DevNull); err == nil {
+		t.Fatal("identity threshold 2 should fail")
+	}
+	flags = CmdFlag{IdNoGaps: true}
+	if err := Mymain(&flags, fname, os.DevNull); err == nil {
+		t.Fatal("-idnogaps without -id should fail")
+	}
+}
+
+// TestRefCase has a reference in lower case. The residue names are
+// written as they are in the file, but the compatibility is worked out
+// after everything is upper case, with and without streaming.
+func TestRefCase(t *testing.T) {
+	fname, err := common.WrtTemp("> ref\nacDE\n> s2\nACDE\n> s3\nAcDF\n")
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	outname := fname + ".csv"
+	defer os.Remove(outname)
+	for _, stream := range []bool{false, true} {
+		flags := CmdFlag{RefSeq: "ref", Stream: stream}
+		if err := Mymain(&flags, fname, outname); err != nil {
+			t.Fatal(err)
+		}
+		out, err := os.ReadFile(outname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(out), "\n")
+		for i, want := range []string{"a,1.00", "c,1.00", "D,1.00", "E,0.50"} {
+			if !strings.HasSuffix(lines[i+1], ","+want) {
+				t.Fatal("stream", stream, "line", i+1, "wanted", want, "got", lines[i+1])
+			}
+		}
+	}
+}
+
+// TestBadSymbol has a byte that cannot be upper cased into the alphabet.
+// It should come back as an error, not be counted.
+func TestBadSymbol(t *testing.T) {
+	fname, err := common.WrtTemp("> a\nAC\xe9D\n> b\nACDE\n")
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	outname := fname + ".csv"
+	defer os.Remove(outname)
+	for _, stream := range []bool{false, true} {
+		flags := CmdFlag{Stream: stream}
+		if err := Mymain(&flags, fname, outname); err == nil {
+			t.Error("stream", stream, "bad symbol should give an error")
+		}
+	}
+}
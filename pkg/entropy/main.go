@@ -2,21 +2,26 @@
 package entropy
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/andrew-torda/seq_compat/pkg/seq"
 )
 
 type ntrpyargs struct {
-	entropy []float32 // sequence entropy
-	gapfrac []float32 // fraction of gap entries in column
-	compat  []float32 // compatibility of reference sequence
-	outfile string    // write to a file or standard input // Check.. is this used ? X
-	refseq  []byte    // nil or reference sequence
-	offset  int       // residue number offset on output
+	entropy  []float32 // sequence entropy
+	gapfrac  []float32 // fraction of gap entries in column
+	termfrac []float32 // nil or fraction of terminal gaps in column
+	neff     []float32 // nil or effective number of sequences in column
+	compat   []float32 // compatibility of reference sequence
+	outfile  string    // write to a file or standard input // Check.. is this used ? X
+	refseq   []byte    // nil or reference sequence
+	offset   int       // residue number offset on output
 }
 
 // warnExists checks if a filename exists and prints a warning
@@ -90,6 +95,12 @@ func writeChimera(fname string, args *ntrpyargs) error {
 // filename is "-", write to standard output.
 func writeNtrpy(args *ntrpyargs) error {
 	headings1 := `"res num","entropy","%frac non-gap"`
+	if args.termfrac != nil {
+		headings1 += `,"%frac terminal gap"`
+	}
+	if args.neff != nil {
+		headings1 += `,"neff"`
+	}
 	if args.refseq != nil {
 		headings1 += `,"res name","compatibility"`
 	}
@@ -114,6 +125,12 @@ func writeNtrpy(args *ntrpyargs) error {
 	fmt.Fprintln(fp, headings1)
 	for i, v := range args.entropy {
 		fmt.Fprintf(fp, "%d,%.2f,%.2f", i+1+args.offset, v, 1-args.gapfrac[i])
+		if args.termfrac != nil {
+			fmt.Fprintf(fp, ",%.2f", args.termfrac[i])
+		}
+		if args.neff != nil {
+			fmt.Fprintf(fp, ",%.1f", args.neff[i])
+		}
 		if args.refseq != nil {
 			fmt.Fprintf(fp, ",%c,%.2f", args.refseq[i], args.compat[i])
 		}
@@ -123,18 +140,33 @@ func writeNtrpy(args *ntrpyargs) error {
 }
 
 type CmdFlag struct {
-	Chimera     string // write output in format for chimera
-	Offset      int    // Add this to the residue numbering on output
-	GapsAreChar bool   // Do we keep gaps ? Are gaps a valid symbol ?
-	NSym        int    // Set the number of symbols in sequences
-	RefSeq      string // A reference seq, whose compatibility will be calculated
-	Time        bool   // do we want to print out run time ?
+	Alphabet    string  // protein, dna or rna, guessed if empty
+	Chimera     string  // write output in format for chimera
+	Offset      int     // Add this to the residue numbering on output
+	GapsAreChar bool    // Do we keep gaps ? Are gaps a valid symbol ?
+	GapChars    string  // Extra gap characters, like ".~"
+	IdThresh    float64 // Weight sequences by neighbours at this identity, 0 for off
+	IdNoGaps    bool    // With IdThresh, only compare sites where both have residues
+	InFormat    string  // Input format, guessed from the file if empty
+	MatchOnly   bool    // A2M/A3M input, only use match states
+	NSym        int     // Set the number of symbols in sequences
+	NWorker     int     // Goroutines for reading fasta and for counting
+	Prior       string  // Pseudocounts, as for seq.PriorByName, "" for none
+	QualCutoff  int     // fastq input, ignore bases with lower quality
+	QualProb    bool    // fastq input, weight bases by quality
+	RefSeq      string  // A reference seq, whose compatibility will be calculated
+	Stream      bool    // Count sequences as they are read, do not keep them
+	TermGaps    bool    // Terminal gaps are weighted and reported separately
+	TermGapWt   float64 // With TermGaps, weight of terminal gaps, 0 ignores them
+	Time        bool    // do we want to print out run time ?
+	Weight      bool    // Henikoff position-based sequence weights
 }
 
 // Mymain is the main function for calculating entropy and writing to a file
 func Mymain(flags *CmdFlag, infile, outfile string) error {
 	var err error
-	s_opts := &seq.Options{}
+	s_opts := &seq.Options{A2M: flags.MatchOnly, InFormat: flags.InFormat,
+		GapChars: flags.GapChars, NWorker: flags.NWorker}
 	if flags.Time {
 		startTime := time.Now()
 		end := func() { // Wrapping in a closure is helpful. Gives the right time.
@@ -146,23 +178,30 @@ func Mymain(flags *CmdFlag, infile, outfile string) error {
 		outfile: outfile, //       to the printing function later
 		offset:  flags.Offset}
 
-	seqgrp, err := seq.Readfile(infile, s_opts)
-	if err != nil {
-		return (fmt.Errorf("Fail reading sequences: %w", err))
+	if flags.TermGaps && (flags.TermGapWt < 0 || flags.TermGapWt > 1) {
+		return fmt.Errorf("terminal gap weight %g should be from 0 to 1", flags.TermGapWt)
 	}
-
-	if flags.RefSeq != "" {
-		if ndxSeq := seqgrp.FindNdx(flags.RefSeq); ndxSeq == -1 {
-			return (fmt.Errorf(`Cannot find ref sequence "%s"\n`, flags.RefSeq))
-		} else {
-			ntrpyargs.refseq = seqgrp.SeqSlc()[ndxSeq].GetSeq()
-			ntrpyargs.compat = seqgrp.Compat(ntrpyargs.refseq, flags.GapsAreChar)
-		}
+	if flags.IdThresh < 0 || flags.IdThresh > 1 {
+		return fmt.Errorf("identity threshold %g should be from 0 to 1", flags.IdThresh)
+	}
+	if flags.IdThresh > 0 && flags.Weight {
+		return errors.New("choose Henikoff or identity weights, not both")
 	}
-	seqgrp.Upper()
-	ntrpyargs.gapfrac = seqgrp.GapFrac()
-	ntrpyargs.entropy = make([]float32, seqgrp.GetLen())
-	seqgrp.Entropy(flags.GapsAreChar, ntrpyargs.entropy)
+	if flags.IdNoGaps && flags.IdThresh == 0 {
+		return errors.New("-idnogaps only means something with -id")
+	}
+	var prof *seq.Profile
+	if flags.Stream {
+		prof, err = streamProfile(flags, infile, s_opts, ntrpyargs)
+	} else {
+		prof, err = readProfile(flags, infile, s_opts, ntrpyargs)
+	}
+	if err != nil {
+		return err
+	}
+	ntrpyargs.gapfrac = prof.GapFrac()
+	ntrpyargs.entropy = make([]float32, prof.Len())
+	prof.Entropy(ntrpyargs.entropy)
 
 	if err = writeNtrpy(ntrpyargs); err != nil {
 		return err
@@ -174,3 +213,142 @@ func Mymain(flags *CmdFlag, infile, outfile string) error {
 	}
 	return nil
 }
+
+// readProfile reads the whole alignment and makes the profile from it.
+// It sets the reference sequence, its compatibility, terminal gaps and
+// Neff in args.
+func readProfile(flags *CmdFlag, infile string, s_opts *seq.Options, args *ntrpyargs) (*seq.Profile, error) {
+	seqgrp, err := seq.Readfile(infile, s_opts)
+	if err != nil {
+		return nil, fmt.Errorf("Fail reading sequences: %w", err)
+	}
+	seqgrp.KeepMatch() // Does nothing unless input was A2M/A3M
+	ndxSeq := -1
+	if flags.RefSeq != "" { // Before Upper, so residues are written as in the file
+		if ndxSeq = seqgrp.FindNdx(flags.RefSeq); ndxSeq == -1 {
+			return nil, fmt.Errorf(`Cannot find ref sequence "%s"\n`, flags.RefSeq)
+		}
+		args.refseq = bytes.Clone(seqgrp.SeqSlc()[ndxSeq].GetSeq())
+	}
+	if err := seqgrp.Upper(); err != nil { // Before anything looks at the symbols
+		return nil, err
+	}
+	switch { // Qualities only matter for fastq input
+	case flags.QualProb:
+		seqgrp.SetQualWeight(seq.QualProb, 0)
+	case flags.QualCutoff > 0:
+		seqgrp.SetQualWeight(seq.QualCutoff, flags.QualCutoff)
+	}
+	if flags.Alphabet != "" {
+		alpha, err := seq.AlphabetByName(flags.Alphabet)
+		if err != nil {
+			return nil, err
+		}
+		seqgrp.SetAlphabet(alpha)
+	}
+	if err := seqgrp.SetNSym(flags.NSym); err != nil {
+		return nil, err
+	}
+	seqgrp.SetNWorker(flags.NWorker)
+	if flags.TermGaps {
+		seqgrp.SetTermGapWeight(float32(flags.TermGapWt))
+		args.termfrac = seqgrp.TermGapFrac()
+	}
+	if flags.Weight {
+		if err := seqgrp.SetSeqWeights(seqgrp.HenikoffWeights()); err != nil {
+			return nil, err
+		}
+	}
+	if flags.IdThresh > 0 {
+		gaps := seq.GapsMatch
+		if flags.IdNoGaps {
+			gaps = seq.GapsIgnored
+		}
+		if err := seqgrp.SetSeqWeights(seqgrp.IdentityWeights(float32(flags.IdThresh), gaps)); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "Neff %.1f from %d sequences\n", seqgrp.Neff(), seqgrp.NSeq())
+	}
+	if flags.Prior != "" {
+		prior, err := seq.PriorByName(flags.Prior, seqgrp.Alphabet())
+		if err != nil {
+			return nil, err
+		}
+		seqgrp.SetPrior(prior)
+	}
+
+	prof, err := seq.NewProfile(seqgrp, flags.GapsAreChar)
+	if err != nil {
+		return nil, err
+	}
+	if flags.IdThresh > 0 {
+		args.neff = prof.SiteNeff()
+	}
+	if ndxSeq != -1 {
+		refWt := float32(1)
+		if w := seqgrp.SeqWeights(); w != nil {
+			refWt = w[ndxSeq]
+		}
+		args.compat = prof.CompatWt(seqgrp.SeqSlc()[ndxSeq].GetSeq(), refWt)
+	}
+	return prof, nil
+}
+
+// streamProfile counts sequences as they are read, so the alignment
+// never has to fit in memory. Only the reference sequence is kept. It
+// is found as by FindNdx, an exact accession or entry name first, or
+// else the first comment containing flags.RefSeq. The output is the
+// same as from readProfile.
+func streamProfile(flags *CmdFlag, infile string, s_opts *seq.Options, args *ntrpyargs) (*seq.Profile, error) {
+	if flags.MatchOnly || flags.QualProb || flags.QualCutoff > 0 || flags.Weight || flags.IdThresh > 0 {
+		return nil, errors.New("streaming does not work with A2M match states, fastq qualities or sequence weights")
+	}
+	acc := seq.NewAccumulator()
+	acc.FoldCase()
+	if flags.Alphabet != "" {
+		alpha, err := seq.AlphabetByName(flags.Alphabet)
+		if err != nil {
+			return nil, err
+		}
+		acc.SetAlphabet(alpha)
+	}
+	acc.SetNSym(flags.NSym)
+	if flags.TermGaps {
+		acc.SetTermGapWeight(float32(flags.TermGapWt))
+	}
+	ref := strings.TrimLeft(flags.RefSeq, " >	")
+	exact := false
+	add := func(cmmt string, s []byte) error {
+		if ref != "" && !exact {
+			h := seq.ParseHeader(cmmt)
+			if exact = h.Accession == ref || h.EntryName == ref; exact {
+				args.refseq = bytes.Clone(s)
+			} else if args.refseq == nil && strings.Contains(cmmt, ref) {
+				args.refseq = bytes.Clone(s)
+			}
+		}
+		return acc.Add(cmmt, s)
+	}
+	if err := seq.StreamFile(infile, s_opts, add); err != nil {
+		return nil, fmt.Errorf("Fail reading sequences: %w", err)
+	}
+	if ref != "" && args.refseq == nil {
+		return nil, fmt.Errorf(`Cannot find ref sequence "%s"\n`, flags.RefSeq)
+	}
+	args.termfrac = acc.TermGapFrac()
+	prof, err := acc.Profile(flags.GapsAreChar)
+	if err != nil {
+		return nil, err
+	}
+	if flags.Prior != "" {
+		prior, err := seq.PriorByName(flags.Prior, acc.Alphabet())
+		if err != nil {
+			return nil, err
+		}
+		prof = prof.WithPrior(prior)
+	}
+	if args.refseq != nil {
+		args.compat = prof.Compat(bytes.ToUpper(args.refseq))
+	}
+	return prof, nil
+}
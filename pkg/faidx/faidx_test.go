@@ -0,0 +1,44 @@
+// 17 Oct 2026
+
+package faidx_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/faidx"
+	"github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// TestMymain pulls out a whole sequence, one with a colon in its name
+// and a region, then checks we can read them back.
+func TestMymain(t *testing.T) {
+	fname, err := common.WrtTemp(">a\nACDEFG\nHIKLMN\n>sp:x y\nQQQ\n>c\nPPPPPP\nRR\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+	defer os.Remove(fname + seq.FaiExt)
+	outname := fname + ".out"
+	defer os.Remove(outname)
+	regions := []string{"a", "sp:x", "c:4-7", "a:11"}
+	if err := faidx.Mymain(fname, regions, outname); err != nil {
+		t.Fatal(err)
+	}
+	seqgrp, err := seq.Readfile(outname, &seq.Options{DiffLenSeq: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ACDEFGHIKLMN", "QQQ", "PPPR", "MN"}
+	for i, ss := range seqgrp.SeqSlc() {
+		if string(ss.GetSeq()) != want[i] || ss.Cmmt() != regions[i] {
+			t.Fatal("wanted", regions[i], want[i], "got", ss.Cmmt(), string(ss.GetSeq()))
+		}
+	}
+	for _, r := range []string{"a:0-3", "a:5-20", "nothere", "a:x-y"} {
+		if err := faidx.Mymain(fname, []string{r}, outname); err == nil {
+			t.Fatal("should fail on region", r)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// 17 Oct 2026
+// faidx pulls sequences, or pieces of them, out of a big fasta file
+// using a samtools-style .fai index, without reading the whole file.
+
+package faidx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+const cPerLine = 60 // residues per line on output
+
+// region is a piece of a sequence. start and end count from zero and
+// end is included. end < 0 means the end of the sequence.
+type region struct {
+	name       string
+	start, end int64
+}
+
+// parseRegion understands regions as samtools writes them, "name",
+// "name:from-to" or "name:from", where from and to count from one.
+// A name with a colon in it is found if it is in the index.
+func parseRegion(s string, fai *seq.Fai) (region, error) {
+	const badRegion = "region \"%s\" should look like name:from-to"
+	if _, ok := fai.Entry(s); ok {
+		return region{name: s, end: -1}, nil
+	}
+	i := strings.LastIndexByte(s, ':')
+	if i == -1 {
+		return region{name: s, end: -1}, nil
+	}
+	r := region{name: s[:i], end: -1}
+	from, to, hasTo := strings.Cut(strings.ReplaceAll(s[i+1:], ",", ""), "-")
+	n, err := strconv.ParseInt(from, 10, 64)
+	if err != nil || n < 1 {
+		return r, fmt.Errorf(badRegion, s)
+	}
+	r.start = n - 1
+	if hasTo {
+		if n, err = strconv.ParseInt(to, 10, 64); err != nil || n < 1 {
+			return r, fmt.Errorf(badRegion, s)
+		}
+		r.end = n - 1
+	}
+	return r, nil
+}
+
+// writeSeq writes one sequence in fasta format.
+func writeSeq(w io.Writer, cmmt string, s []byte) {
+	fmt.Fprintf(w, ">%s\n", cmmt)
+	for len(s) > cPerLine {
+		fmt.Fprintf(w, "%s\n", s[:cPerLine])
+		s = s[cPerLine:]
+	}
+	fmt.Fprintf(w, "%s\n", s)
+}
+
+// WriteIndex builds the index for a fasta file and writes it to
+// fname.fai, replacing any old one.
+func WriteIndex(fname string) error {
+	fp, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+	fai, err := seq.BuildFai(fp)
+	if err != nil {
+		return fmt.Errorf("indexing %s: %w", fname, err)
+	}
+	faiFp, err := os.Create(fname + seq.FaiExt)
+	if err != nil {
+		return err
+	}
+	if err := fai.Write(faiFp); err != nil {
+		faiFp.Close()
+		return err
+	}
+	return faiFp.Close()
+}
+
+// Mymain writes the regions from fname to outfile. If there are no
+// regions, it just (re)builds the index. If there is no outfile or it
+// is "-", write to standard output.
+func Mymain(fname string, regions []string, outfile string) error {
+	if len(regions) == 0 {
+		return WriteIndex(fname)
+	}
+	ix, err := seq.OpenIndexed(fname)
+	if err != nil {
+		return err
+	}
+	defer ix.Close()
+	var fp io.Writer = os.Stdout
+	if outfile != "" && outfile != "-" {
+		f, err := os.Create(outfile)
+		if err != nil {
+			return fmt.Errorf("output file %v: %w", outfile, err)
+		}
+		defer f.Close()
+		fp = f
+	}
+	bw := bufio.NewWriter(fp)
+	for _, s := range regions {
+		r, err := parseRegion(s, ix.Fai())
+		if err != nil {
+			return err
+		}
+		b, err := ix.Fetch(r.name, r.start, r.end)
+		if err != nil {
+			return err
+		}
+		writeSeq(bw, s, b)
+	}
+	return bw.Flush()
+}
@@ -4,3 +4,5 @@ var ExtractSeqX = extractSeqX
 var InnerCosSim = innerCosSim
 var KlFromSeqX = klFromSeqX
 var CalcInner = calcInner
+
+func (seqX *SeqX) Counts() [][]float32 { return seqX.counts.Mat }
@@ -8,11 +8,13 @@ import (
 	"io/ioutil"
 	"math"
 	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/andrew-torda/seq_compat/pkg/kl"
 	"github.com/andrew-torda/seq_compat/pkg/randseq"
 	"github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
 )
 
 // TestTiny - just make sure we can open and read some files.
@@ -30,7 +32,7 @@ func TestTiny(t *testing.T) {
 	randseq.RandSeqMain(&args)
 	tmpfile.Close()
 	var flags CmdFlag
-	if err = Mymain(&flags, tmpfile.Name(), tmpfile.Name(), "boo"); err != nil {
+	if err = Mymain(&flags, tmpfile.Name(), tmpfile.Name(), filepath.Join(t.TempDir(), "boo")); err != nil {
 		t.Fatalf("broke on tiny set: %v", err)
 	}
 }
@@ -72,6 +74,126 @@ func TestSimple1(t *testing.T) {
 	}
 }
 
+// TestNSym checks -n is checked against the symbols in the files.
+func TestNSym(t *testing.T) {
+	flags := CmdFlag{NSym: 1}
+	var seqX SeqX
+	if err := ExtractSeqX(seq.Str2SeqGrp([]string{"ab", "bb"}), &seqX, &flags); err == nil {
+		t.Fatal("one symbol should be too few")
+	}
+	flags.NSym = 4
+	if err := ExtractSeqX(seq.Str2SeqGrp([]string{"ab", "bb"}), &seqX, &flags); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWeight has sequences AA, AA and AB. With Henikoff weights, B in
+// column 1 (row 1) goes from 1/3 to 5/12. Weights cannot stream and
+// -idnogaps needs -id.
+func TestWeight(t *testing.T) {
+	for _, w := range []struct {
+		weight bool
+		want   float32
+	}{{false, 1. / 3}, {true, 5. / 12}} {
+		var seqX SeqX
+		flags := CmdFlag{Weight: w.weight}
+		if err := ExtractSeqX(seq.Str2SeqGrp([]string{"AA", "AA", "AB"}), &seqX, &flags); err != nil {
+			t.Fatal(err)
+		}
+		if got := seqX.Counts()[1][1]; !approxEqual(got, w.want) {
+			t.Fatal("weight", w.weight, "wanted", w.want, "got", got)
+		}
+	}
+	flags := CmdFlag{Weight: true, Stream: true}
+	if err := Mymain(&flags, "testdata/a.fa", "testdata/b.fa", os.DevNull); err == nil {
+		t.Fatal("weights and streaming should not go together")
+	}
+	flags = CmdFlag{IdNoGaps: true}
+	if err := Mymain(&flags, "testdata/a.fa", "testdata/b.fa", os.DevNull); err == nil {
+		t.Fatal("-idnogaps without -id should fail")
+	}
+}
+
+// TestPrior gives C at site 0 a frequency, although only one of three
+// sequences has it, and checks that streaming does the same.
+func TestPrior(t *testing.T) {
+	var seqX SeqX
+	flags := CmdFlag{Prior: "uniform"}
+	if err := ExtractSeqX(seq.Str2SeqGrp([]string{"AT", "AT", "CT"}), &seqX, &flags); err != nil {
+		t.Fatal(err)
+	}
+	if f := seqX.Counts()[1][0]; f == 0 {
+		t.Fatal("C at site 0 should have a pseudocount")
+	}
+	var out [2][]byte
+	for i, stream := range []bool{false, true} {
+		tmpfile, err := os.CreateTemp("", "delete_me")
+		if err != nil {
+			t.Fatalf("Broke on tempfile %v", err)
+		}
+		tmpfile.Close()
+		defer os.Remove(tmpfile.Name())
+		flags := CmdFlag{Prior: "blosum62", Stream: stream}
+		if err := Mymain(&flags, "testdata/a.fa", "testdata/b.fa", tmpfile.Name()); err != nil {
+			t.Fatal(err)
+		}
+		if out[i], err = os.ReadFile(tmpfile.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out[0]) != string(out[1]) {
+		t.Fatal("streaming changed the output with a prior")
+	}
+}
+
+// TestNWorker checks that reading and counting with several
+// goroutines gives the same output as one.
+func TestNWorker(t *testing.T) {
+	var out [2][]byte
+	for i, nworker := range []int{1, 4} {
+		outname := filepath.Join(t.TempDir(), "kl.csv")
+		flags := CmdFlag{NWorker: nworker}
+		if err := Mymain(&flags, "testdata/a.fa", "testdata/b.fa", outname); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if out[i], err = os.ReadFile(outname); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out[0]) != string(out[1]) {
+		t.Fatal("more workers changed the output")
+	}
+}
+
+// TestStream compares output with and without streaming. A missing
+// file must not leave the other one waiting for its symbols.
+func TestStream(t *testing.T) {
+	var out [2][]byte
+	for i, stream := range []bool{false, true} {
+		tmpfile, err := ioutil.TempFile("", "delete_me")
+		if err != nil {
+			t.Fatalf("Broke on tempfile %v", err)
+		}
+		tmpfile.Close()
+		defer os.Remove(tmpfile.Name())
+		flags := CmdFlag{Stream: stream}
+		if err := Mymain(&flags, "testdata/a.fa", "testdata/b.fa", tmpfile.Name()); err != nil {
+			t.Fatal(err)
+		}
+		if out[i], err = os.ReadFile(tmpfile.Name()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(out[0]) != string(out[1]) {
+		t.Fatal("streaming changed the output")
+	}
+	flags := CmdFlag{Stream: true}
+	if err := Mymain(&flags, "testdata/a.fa", "/notexist", os.DevNull); err == nil {
+		t.Fatal("missing file should provoke an error")
+	}
+}
+
 func approxEqual(x, y float32) bool {
 	d := x - y
 	const eps = 0.0001
@@ -187,3 +309,17 @@ func TestInnerCosSim(t *testing.T) {
 
 	}
 }
+
+// TestBadSymbol has a byte in the second file that cannot be upper cased
+// into the alphabet. It should come back as an error.
+func TestBadSymbol(t *testing.T) {
+	fname, err := common.WrtTemp("> a\nAC\xe9D\n> b\nACDE\n")
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	var flags CmdFlag
+	if err := Mymain(&flags, "testdata/a.fa", fname, os.DevNull); err == nil {
+		t.Error("bad symbol should give an error")
+	}
+}
@@ -12,14 +12,21 @@ import (
 
 	"github.com/andrew-torda/matrix"
 	"github.com/andrew-torda/seq_compat/pkg/seq"
-	"github.com/andrew-torda/seq_compat/pkg/seq/common"
 )
 
 // CmdFlag is literally command line flags after parsing
 type CmdFlag struct {
-	Offset      int  // Add this to the residue numbering on output
-	GapsAreChar bool // Do we keep gaps ? Are gaps a valid symbol ?
-	NSym        int  // Set the number of symbols in sequences
+	Offset      int     // Add this to the residue numbering on output
+	GapsAreChar bool    // Do we keep gaps ? Are gaps a valid symbol ?
+	NSym        int     // Set the number of symbols in sequences
+	InFormat    string  // Input format, guessed from the file if empty
+	GapChars    string  // Extra gap characters, like ".~"
+	NWorker     int     // Goroutines for reading and counting each file
+	Stream      bool    // Count sequences as they are read, do not keep them
+	Weight      bool    // Henikoff position-based sequence weights
+	IdThresh    float64 // Weight sequences by neighbours at this identity, 0 for off
+	IdNoGaps    bool    // With IdThresh, only compare sites where both have residues
+	Prior       string  // Pseudocounts, as for seq.PriorByName, "" for none
 }
 
 // seqX are the elements of a SeqGrp structure which are
@@ -40,21 +47,84 @@ func (seqx *SeqX) GetLen() int { return seqx.len }
 // seqX gets the relevant information for KL calculation from a sequence
 // group. It only goes into its own function so it can be called
 // during testing.
+// If flags.NSym is set, it is the number of symbols and so the base
+// for logarithms. With flags.Weight, sequences have Henikoff weights
+// and with flags.IdThresh, identity weights. flags.Prior gives the
+// pseudocounts.
 func extractSeqX(seqgrp *seq.SeqGrp, seqX *SeqX, flags *CmdFlag) error {
-	var err error
 	var gapsAreChars = false
 
-	logbase := seqgrp.GetLogBase(gapsAreChars)
+	if err := seqgrp.SetNSym(flags.NSym); err != nil {
+		return err
+	}
+	seqgrp.SetNWorker(flags.NWorker)
+	if flags.Weight {
+		if err := seqgrp.SetSeqWeights(seqgrp.HenikoffWeights()); err != nil {
+			return err
+		}
+	}
+	if flags.IdThresh > 0 {
+		gaps := seq.GapsMatch
+		if flags.IdNoGaps {
+			gaps = seq.GapsIgnored
+		}
+		if err := seqgrp.SetSeqWeights(seqgrp.IdentityWeights(float32(flags.IdThresh), gaps)); err != nil {
+			return err
+		}
+	}
+	if flags.Prior != "" {
+		prior, err := seq.PriorByName(flags.Prior, seqgrp.Alphabet())
+		if err != nil {
+			return err
+		}
+		seqgrp.SetPrior(prior)
+	}
+	prof, err := seq.NewProfile(seqgrp, gapsAreChars)
+	if err != nil {
+		return err
+	}
+	profToSeqX(prof, seqX)
+	return nil
+}
+
+// profToSeqX copies what we need from a profile.
+func profToSeqX(prof *seq.Profile, seqX *SeqX) {
+	seqX.len = prof.Len()
+	seqX.counts = prof.Freqs()
+	seqX.revmap = prof.Symbols()
+	seqX.nseq = prof.NSeq()
+	seqX.logbase = prof.LogBase()
+	seqX.gapMapping = prof.GapMapping()
+}
+
+// streamSeqX is getseqX for flags.Stream. Each sequence is counted as
+// it is read, so the file never has to fit in memory. The symbols seen
+// are merged with the other file's before the profile is made, as
+// SetSymUsedWithChan does.
+func streamSeqX(flags *CmdFlag, infile string, seqX *SeqX, s_opts *seq.Options,
+	frmMrgChn chan [seq.MaxSym]bool, toMrgChn chan [seq.MaxSym]bool) error {
+	acc := seq.NewAccumulator()
+	acc.FoldCase()
+	acc.SetNSym(flags.NSym)
+	err := seq.StreamFile(infile, s_opts, acc.Add)
+	toMrgChn <- acc.SymUsed() // even after an error, or the other file waits
+	acc.UseSymbols(<-frmMrgChn)
+	if err != nil {
+		return fmt.Errorf("Fail reading sequences: %w", err)
+	}
+	const gapsAreChars = false
+	prof, err := acc.Profile(gapsAreChars)
 	if err != nil {
 		return err
 	}
-	seqgrp.UsageFrac(gapsAreChars)
-	seqX.len = seqgrp.GetLen()
-	seqX.counts = seqgrp.GetCounts()
-	seqX.revmap = seqgrp.GetRevmap()
-	seqX.nseq = seqgrp.NSeq()
-	seqX.logbase = logbase
-	seqX.gapMapping = seqgrp.GetMapping(common.GapChar)
+	if flags.Prior != "" {
+		prior, err := seq.PriorByName(flags.Prior, acc.Alphabet())
+		if err != nil {
+			return err
+		}
+		prof = prof.WithPrior(prior)
+	}
+	profToSeqX(prof, seqX)
 	return nil
 }
 
@@ -74,7 +144,11 @@ func getseqX(wg *sync.WaitGroup, flags *CmdFlag, infile string, seqX *SeqX,
 		<-frmMrgChn
 	}
 
-	s_opts := &seq.Options{}
+	s_opts := &seq.Options{InFormat: flags.InFormat, GapChars: flags.GapChars, NWorker: flags.NWorker}
+	if flags.Stream {
+		*err = streamSeqX(flags, infile, seqX, s_opts, frmMrgChn, toMrgChn)
+		return
+	}
 
 	seqgrp, e := seq.Readfile(infile, s_opts)
 	if e != nil {
@@ -87,7 +161,11 @@ func getseqX(wg *sync.WaitGroup, flags *CmdFlag, infile string, seqX *SeqX,
 		bailout()
 		return
 	}
-	seqgrp.Upper()
+	if e := seqgrp.Upper(); e != nil {
+		*err = fmt.Errorf("%s: %w", infile, e)
+		bailout()
+		return
+	}
 	seqgrp.SetSymUsedWithChan(frmMrgChn, toMrgChn)
 	*err = extractSeqX(seqgrp, seqX, flags)
 }
@@ -143,7 +221,9 @@ type klIn struct {
 // kl calculates the kullbach-leibler distance
 // When one of the distributions goes to zero, divergence goes to
 // infinity. Use a pseudo-count philosophy.
-// We have N sequences for distribution q. We say the frequency is less
+// With flags.Prior, the frequencies already have pseudocounts and q is
+// only zero for symbols the prior does not know, like X. Otherwise,
+// we have N sequences for distribution q. We say the frequency is less
 // than 1/ N. We say the frequency is 1 / (N + 1).
 func kl(kl_in *klIn, kl []float32) {
 	logbase := math.Log(float64(kl_in.logbase))
@@ -304,6 +384,18 @@ func writeKl(wrtr io.WriteCloser, numresults *numResults, offset int) {
 func Mymain(flags *CmdFlag, fileP, fileQ, outfile string) (err error) {
 	var seqXP, seqXQ SeqX
 	var wrtr io.WriteCloser
+	if flags.Stream && (flags.Weight || flags.IdThresh > 0) {
+		return errors.New("sequence weights need the whole file, so cannot be used when streaming")
+	}
+	if flags.IdThresh < 0 || flags.IdThresh > 1 {
+		return fmt.Errorf("identity threshold %g should be from 0 to 1", flags.IdThresh)
+	}
+	if flags.IdThresh > 0 && flags.Weight {
+		return errors.New("choose Henikoff or identity weights, not both")
+	}
+	if flags.IdNoGaps && flags.IdThresh == 0 {
+		return errors.New("-idnogaps only means something with -id")
+	}
 	if err := readtwofiles(flags, fileP, fileQ, &seqXP, &seqXQ); err != nil {
 		return err
 	}
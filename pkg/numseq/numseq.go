@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/andrew-torda/seq_compat/pkg/unzip"
 	"github.com/edsrzf/mmap-go"
 )
 
@@ -50,12 +51,42 @@ func ByReading(rdr io.ReadSeeker) (int, error) {
 	return count, nil
 }
 
+// ByStream reads from a source which cannot seek, such as a
+// decompression stream or a pipe, and counts ">".
+func ByStream(rdr io.Reader) (int, error) {
+	const bsize = 64 * 1024
+	var buf [bsize]byte
+	count := 0
+	for {
+		n, err := rdr.Read(buf[:])
+		count += bytes.Count(buf[:n], []byte(">"))
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
 // FromFile opens a file and returns the number of sequences by counting ">".
+// If the file is gzip or bzip2 compressed, we count while decompressing.
+// A filename of "-" means standard input.
 func FromFile(fname string) (int, error) {
-	fp, err := os.Open(fname)
+	if fname == "-" {
+		rdr, _, err := unzip.Stream(os.Stdin)
+		if err != nil {
+			return 0, err
+		}
+		return ByStream(rdr)
+	}
+	rdr, kind, err := unzip.Open(fname)
 	if err != nil {
 		return 0, err
 	}
-	defer fp.Close()
-	return ByMmap(fp)
+	defer rdr.Close()
+	if kind != unzip.None {
+		return ByStream(rdr)
+	}
+	return ByMmap(rdr.(*os.File))
 }
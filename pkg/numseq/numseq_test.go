@@ -1,6 +1,7 @@
 package numseq_test
 
 import (
+	"compress/gzip"
 	"io"
 	"io/ioutil"
 	"os"
@@ -62,6 +63,28 @@ func TestFrompointer(t *testing.T) {
 
 }
 
+// TestFromGzip checks we can count sequences in a compressed file
+func TestFromGzip(t *testing.T) {
+	f_tmp, err := ioutil.TempFile("", "_del_me_testing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f_tmp.Name())
+	zw := gzip.NewWriter(f_tmp)
+	args := smalltestArg
+	args.Wrtr = zw
+	if err := randseq.RandSeqMain(&args); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	f_tmp.Close()
+	if i, err := numseq.FromFile(f_tmp.Name()); err != nil {
+		t.Fatal(err)
+	} else if i != smalltestArg.Nseq {
+		t.Fatal("Expected", smalltestArg.Nseq, "got", i)
+	}
+}
+
 type fToBench func(string) (int, error)
 
 func dobench(b *testing.B, f fToBench) {
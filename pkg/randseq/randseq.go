@@ -4,10 +4,14 @@
 package randseq
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"math/rand"
+	"strings"
 	"sync"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq"
 )
 
 const (
@@ -35,6 +39,7 @@ type RandSeqArgs struct {
 	NoGap   bool      // Do not add gaps
 	NoSpace bool      // Do not add spaces
 	MkErr   bool      // Add an error, by changing a length
+	Format  string    // Output format, default fasta
 }
 
 var letters []byte
@@ -91,6 +96,9 @@ func writeseq(sChan <-chan []byte, args *RandSeqArgs, wg *sync.WaitGroup) {
 
 // RandSeqMain writes random sequences to an io.Writer.
 func RandSeqMain(args *RandSeqArgs) error {
+	if args.Format != "" && args.Format != "fasta" {
+		return otherFormat(args)
+	}
 	var wg sync.WaitGroup
 	letters = []byte{'a', 'c', 'd', 'e', 'f', 'g',
 		'h', 'i', 'k', 'l', 'm', 'n', 'p', 'q', 'r', 's', 't', 'v', 'w', 'y'}
@@ -111,3 +119,34 @@ func RandSeqMain(args *RandSeqArgs) error {
 	wg.Wait()
 	return nil
 }
+
+// otherFormat is for output in anything but fasta. We make fasta in
+// memory, read it back in and write it out through the seq package.
+// The alignment formats name each sequence by the first word of its
+// comment, which would always be args.Cmmt, so the words are joined
+// with "_". With a comment of "s", that gives "s_1", "s_2", ...
+func otherFormat(args *RandSeqArgs) error {
+	format, err := seq.Lookup(args.Format)
+	if err != nil {
+		return err
+	}
+	if format.Write == nil {
+		return fmt.Errorf("cannot write %s format", format.Name)
+	}
+	var buf bytes.Buffer
+	fastaArgs := *args
+	fastaArgs.Wrtr, fastaArgs.Format = &buf, ""
+	if err := RandSeqMain(&fastaArgs); err != nil {
+		return err
+	}
+	s_opts := &seq.Options{DiffLenSeq: true, ZeroLenOK: true}
+	var seqgrp seq.SeqGrp
+	if err := seq.ReadFasta(&buf, &seqgrp, s_opts); err != nil {
+		return err
+	}
+	seqs := seqgrp.SeqSlc()
+	for i := range seqs {
+		seqs[i].SetCmmt(strings.Join(strings.Fields(seqs[i].Cmmt()), "_"))
+	}
+	return format.Write(args.Wrtr, &seqgrp, s_opts)
+}
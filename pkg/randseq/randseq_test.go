@@ -22,3 +22,23 @@ func TestSimple (t *testing.T) {
 		t.Fatal("count >, got ", n, "expected", args.Nseq)
 	}
 }
+
+func TestFormat(t *testing.T) {
+	var sb strings.Builder
+	args := randseq.RandSeqArgs{
+		Wrtr:   &sb,
+		Cmmt:   "s",
+		Nseq:   3,
+		Len:    20,
+		Format: "phylip",
+	}
+	if err := randseq.RandSeqMain(&args); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sb.String(), "3 20\n") {
+		t.Fatalf("phylip output should start with \"3 20\", got %q", sb.String()[:10])
+	}
+	if !strings.Contains(sb.String(), "\ns_3 ") {
+		t.Fatalf("phylip names should be s_1, s_2, ..., got\n%s", sb.String())
+	}
+}
@@ -0,0 +1,155 @@
+// 17 Oct 2026
+// A2M and A3M formats, as written by HHblits, jackhmmer and friends.
+// Both look like fasta, but the case of a letter means something.
+//   - Upper case letters and "-" are match states.
+//   - Lower case letters are insertions. In A2M, "." is a gap in an
+//     insert column.
+//
+// In A2M, every row has the same length. In A3M, the "." are left out,
+// so rows only agree on the number of match states. We read either,
+// by splitting each row into match states and the inserts in front of
+// each match state. Then we either
+//   - pad the inserts with gaps so every row has the longest insert at
+//     that point (the full alignment), or
+//   - throw the inserts away and keep only match columns.
+//
+// The columns are labelled, so one can later keep only the match states.
+
+package seq
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+const a2mInsGap = '.' // gap in an insert column
+
+// isInsert says if a character is an insert state in A2M/A3M.
+func isInsert(c byte) bool { return ('a' <= c && c <= 'z') || c == a2mInsGap }
+
+// splitA3M breaks a row into its match states and the inserts in
+// front of each of them. ins has one more entry than match, for
+// whatever comes after the last match state.
+func splitA3M(s []byte) (match []byte, ins [][]byte) {
+	start := 0
+	for i, c := range s {
+		if isInsert(c) {
+			continue
+		}
+		ins = append(ins, s[start:i])
+		match = append(match, c)
+		start = i + 1
+	}
+	ins = append(ins, s[start:])
+	return match, ins
+}
+
+// ReadA2M reads an alignment in A2M or A3M format. If s_opts.DropInserts
+// is set, only match states are kept. Otherwise, inserts are padded with
+// gaps to give a full alignment. Columns are labelled as match or insert.
+// The remaining options work as for ReadFasta. Inserts are padded to the
+// longest in any sequence, so the whole alignment is needed and it
+// cannot be read by StreamFile.
+func ReadA2M(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const bustMatch = "a2m/a3m seq %d has %d match states, wanted %d"
+	if seqgrp.sink != nil {
+		return errA2MStream
+	}
+	raw := &Options{DiffLenSeq: true, ZeroLenOK: true}
+	if err := readFasta(rdr, seqgrp, raw); err != nil {
+		return err
+	}
+	nseq := len(seqgrp.seqs)
+	matches := make([][]byte, nseq)
+	inserts := make([][][]byte, nseq)
+	for i, ss := range seqgrp.seqs {
+		matches[i], inserts[i] = splitA3M(ss.seq)
+		if len(matches[i]) != len(matches[0]) {
+			return fmt.Errorf(bustMatch, i, len(matches[i]), len(matches[0]))
+		}
+	}
+	nmatch := len(matches[0])
+	maxIns := make([]int, nmatch+1) // longest insert before each match state
+	if !s_opts.DropInserts {
+		for _, ins := range inserts {
+			for k, piece := range ins {
+				if len(piece) > maxIns[k] {
+					maxIns[k] = len(piece)
+				}
+			}
+		}
+	}
+	var matchCol []bool
+	for k := 0; k <= nmatch; k++ {
+		for j := 0; j < maxIns[k]; j++ {
+			matchCol = append(matchCol, false)
+		}
+		if k < nmatch {
+			matchCol = append(matchCol, true)
+		}
+	}
+	for i := range seqgrp.seqs {
+		s := make([]byte, 0, len(matchCol))
+		for k := 0; k <= nmatch; k++ {
+			if maxIns[k] > 0 {
+				for _, c := range inserts[i][k] {
+					if c == a2mInsGap {
+						c = common.GapChar
+					}
+					s = append(s, c)
+				}
+				for j := len(inserts[i][k]); j < maxIns[k]; j++ {
+					s = append(s, common.GapChar)
+				}
+			}
+			if k < nmatch {
+				s = append(s, matches[i][k])
+			}
+		}
+		seqgrp.seqs[i].seq = s
+	}
+	seqgrp.matchCol = matchCol
+	if s_opts.RangeStart != 0 || s_opts.RangeEnd != 0 {
+		if rs, re := s_opts.RangeStart, s_opts.RangeEnd; rs >= 0 && rs <= re && re < len(matchCol) {
+			seqgrp.matchCol = matchCol[rs : re+1]
+		}
+	}
+	if s_opts.RmvGapsRd {
+		seqgrp.matchCol = nil // columns no longer mean anything
+	}
+	return finishGrp(seqgrp, s_opts)
+}
+
+// MatchCols returns a slice saying, for each column, if it is a match
+// state (true) or an insert (false). It is nil if the alignment did not
+// come from an A2M/A3M file.
+func (seqgrp *SeqGrp) MatchCols() []bool { return seqgrp.matchCol }
+
+// NMatch returns the number of match state columns. If columns have
+// not been labelled, every column counts as a match state.
+func (seqgrp *SeqGrp) NMatch() int {
+	if seqgrp.matchCol == nil {
+		return seqgrp.GetLen()
+	}
+	n := 0
+	for _, m := range seqgrp.matchCol {
+		if m {
+			n++
+		}
+	}
+	return n
+}
+
+// KeepMatch removes the insert columns from every sequence and from
+// the column annotation, so that UsageSite, Entropy and friends only
+// see match states. Anything calculated from the old columns is
+// thrown away. If the columns are not labelled, nothing happens.
+func (seqgrp *SeqGrp) KeepMatch() {
+	if seqgrp.matchCol == nil {
+		return
+	}
+	mask := append([]bool(nil), seqgrp.matchCol...)
+	seqgrp.KeepCols(mask)
+}
@@ -0,0 +1,90 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// The same alignment as A3M and as A2M. Four match states, ACDE.
+var (
+	a3m = `>q
+ACDE
+>s1
+AcgCDEk
+>s2
+-CdD-
+`
+	a2m = `>q
+A..C.DE.
+>s1
+AcgC.DEk
+>s2
+-..CdD-.
+`
+)
+
+// TestA3M checks expanding and dropping inserts give the same thing
+// for A2M and A3M.
+func TestA3M(t *testing.T) {
+	full := []string{"A--C-DE-", "AcgC-DEk", "---CdD--"}
+	match := []string{"ACDE", "ACDE", "-CD-"}
+	wantCol := "miimimmi" // m for match, i for insert
+	for _, txt := range []string{a3m, a2m} {
+		var seqgrp SeqGrp
+		if err := ReadA2M(strings.NewReader(txt), &seqgrp, &Options{}); err != nil {
+			t.Fatal(err)
+		}
+		for i, ss := range seqgrp.SeqSlc() {
+			if s := string(ss.GetSeq()); s != full[i] {
+				t.Fatalf("expanded seq %d wanted %s got %s", i, full[i], s)
+			}
+		}
+		var got []byte
+		for _, m := range seqgrp.MatchCols() {
+			if m {
+				got = append(got, 'm')
+			} else {
+				got = append(got, 'i')
+			}
+		}
+		if string(got) != wantCol {
+			t.Fatal("column labels wanted", wantCol, "got", string(got))
+		}
+		if n := seqgrp.NMatch(); n != 4 {
+			t.Fatal("NMatch got", n)
+		}
+
+		seqgrp.KeepMatch()
+		for i, ss := range seqgrp.SeqSlc() {
+			if s := string(ss.GetSeq()); s != match[i] {
+				t.Fatalf("KeepMatch seq %d wanted %s got %s", i, match[i], s)
+			}
+		}
+		if n := seqgrp.GetNSym(); n != 5 {
+			t.Fatal("after KeepMatch counts not recalculated, nsym", n)
+		}
+
+		var dropped SeqGrp
+		s_opts := &Options{DropInserts: true}
+		if err := ReadA2M(strings.NewReader(txt), &dropped, s_opts); err != nil {
+			t.Fatal(err)
+		}
+		for i, ss := range dropped.SeqSlc() {
+			if s := string(ss.GetSeq()); s != match[i] {
+				t.Fatalf("DropInserts seq %d wanted %s got %s", i, match[i], s)
+			}
+		}
+	}
+}
+
+// TestA3MBroken should fail if rows have different numbers of match states
+func TestA3MBroken(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadA2M(strings.NewReader(">a\nACDE\n>b\nACx\n"), &seqgrp, &Options{}); err == nil {
+		t.Fatal("should fail with different numbers of match states")
+	}
+}
@@ -0,0 +1,230 @@
+// 17 Oct 2026
+// Streaming. A SeqGrp keeps every sequence, so the alignment has to fit
+// in memory before anything is counted. For entropy and kl, all we want
+// at the end is the counts at each site. StreamFile hands each sequence
+// to a function as soon as it is read and then reuses the buffer, and
+// an Accumulator adds it to per-site counts. The memory is then the
+// counts, one row for each symbol seen, not the alignment.
+// A Profile from an Accumulator is the same as one from NewProfile on
+// the same sequences with the same settings.
+
+package seq
+
+import (
+	"fmt"
+
+	"github.com/andrew-torda/matrix"
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// SeqFunc is given each sequence by StreamFile. s is only valid until
+// it returns, so copy anything you want to keep. Returning an error
+// stops reading.
+type SeqFunc func(cmmt string, s []byte) error
+
+// StreamFile reads fname like Readfile, but gives each sequence to fn
+// instead of keeping it. Fasta, including compressed fasta, is never
+// held in memory. Other formats are read whole first and then passed
+// to fn one at a time, except A2M/A3M, which is an error. Mmap and
+// NWorker in s_opts are ignored.
+func StreamFile(fname string, s_opts *Options, fn SeqFunc) error {
+	opts := *s_opts
+	opts.Mmap, opts.NWorker = false, 0
+	seqgrp := &SeqGrp{sink: fn}
+	if err := readfile(fname, seqgrp, &opts); err != nil {
+		return err
+	}
+	for _, ss := range seqgrp.seqs {
+		if err := fn(ss.cmmt, ss.seq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Accumulator counts symbols at each site from sequences given one at
+// a time. Make one with NewAccumulator.
+type Accumulator struct {
+	grp      SeqGrp          // no sequences, just the symbols and settings
+	bySym    [MaxSym][]int32 // counts at each site, nil if never seen
+	term     []int32         // terminal gaps at each site, if countTrm
+	remap    [256]uint8      // alphabet's remapping, with case folding
+	nseq     int             // sequences added
+	ncol     int             // length of the first sequence
+	foldCase bool            // count lower case as upper
+	countTrm bool            // SetTermGapWeight was called
+}
+
+// NewAccumulator returns an empty Accumulator.
+func NewAccumulator() *Accumulator {
+	acc := new(Accumulator)
+	acc.setRemap()
+	return acc
+}
+
+// setRemap fills out the table each symbol goes through.
+func (acc *Accumulator) setRemap() {
+	acc.remap = *acc.grp.remapTable()
+	if acc.foldCase {
+		for c := 'a'; c <= 'z'; c++ {
+			acc.remap[c] = acc.remap[c-'a'+'A']
+		}
+	}
+}
+
+// mustBeEmpty stops settings being changed after counting has started.
+func (acc *Accumulator) mustBeEmpty() {
+	if acc.nseq != 0 {
+		panic("program bug, Accumulator settings changed after Add")
+	}
+}
+
+// SetAlphabet is SeqGrp.SetAlphabet. It must be called before Add.
+func (acc *Accumulator) SetAlphabet(a *Alphabet) {
+	acc.mustBeEmpty()
+	acc.grp.SetAlphabet(a)
+	acc.setRemap()
+}
+
+// FoldCase counts lower case as upper case, as if SeqGrp.Upper had
+// been called. It must be called before Add.
+func (acc *Accumulator) FoldCase() {
+	acc.mustBeEmpty()
+	acc.foldCase = true
+	acc.setRemap()
+}
+
+// SetTermGapWeight is SeqGrp.SetTermGapWeight. It must be called
+// before Add.
+func (acc *Accumulator) SetTermGapWeight(w float32) {
+	acc.mustBeEmpty()
+	acc.grp.SetTermGapWeight(w)
+	acc.countTrm = true
+}
+
+// SetNSym is SeqGrp.SetNSym, but it is only checked against the
+// symbols seen when the Profile is made, so it can be called at any
+// time.
+func (acc *Accumulator) SetNSym(n int) { acc.grp.nSym = n }
+
+// Add counts one sequence. Every sequence must be as long as the first.
+// s is not kept, so it fits the SeqFunc from StreamFile. After an
+// error, the counts are not to be trusted.
+func (acc *Accumulator) Add(cmmt string, s []byte) error {
+	if acc.nseq == 0 {
+		acc.ncol = len(s)
+		if acc.countTrm {
+			acc.term = make([]int32, acc.ncol)
+		}
+	} else if len(s) != acc.ncol {
+		return &LengthMismatchError{Record: acc.nseq, Header: cmmt, Want: acc.ncol, Got: len(s)}
+	}
+	for icol, c := range s {
+		c = acc.remap[c]
+		if c >= MaxSym {
+			err := fmt.Errorf("bad symbol %q", s[icol])
+			return &ParseError{Record: acc.nseq, Header: cmmt, Column: icol + 1, Err: err}
+		}
+		row := acc.bySym[c]
+		if row == nil {
+			row = make([]int32, acc.ncol)
+			acc.bySym[c] = row
+		}
+		row[icol]++
+	}
+	if acc.term != nil {
+		first, last := termEnds(s, &acc.remap)
+		for icol := 0; icol < first; icol++ {
+			acc.term[icol]++
+		}
+		for icol := last + 1; icol < len(s); icol++ {
+			acc.term[icol]++
+		}
+	}
+	acc.nseq++
+	return nil
+}
+
+// NSeq is the number of sequences added.
+func (acc *Accumulator) NSeq() int { return acc.nseq }
+
+// Len is the number of sites.
+func (acc *Accumulator) Len() int { return acc.ncol }
+
+// SymUsed says which symbols have been seen, after remapping.
+func (acc *Accumulator) SymUsed() (used [MaxSym]bool) {
+	for c, row := range acc.bySym {
+		used[c] = row != nil || acc.grp.symUsed[c]
+	}
+	return used
+}
+
+// UseSymbols marks symbols as used, even if they were never seen, so
+// the Profile has rows for them. kl uses it so two profiles have the
+// same rows, as SetSymUsedWithChan does for SeqGrps.
+func (acc *Accumulator) UseSymbols(used [MaxSym]bool) {
+	for c, u := range used {
+		acc.grp.symUsed[c] = acc.grp.symUsed[c] || u
+	}
+}
+
+// Alphabet is SeqGrp.Alphabet, guessing the type from the symbols
+// seen so far.
+func (acc *Accumulator) Alphabet() *Alphabet {
+	grp := SeqGrp{alpha: acc.grp.alpha, symUsed: acc.SymUsed(), usedKnwn: true}
+	return grp.Alphabet()
+}
+
+// TermGapFrac is SeqGrp.TermGapFrac. Terminal gaps are only counted
+// if SetTermGapWeight was called, so otherwise it is nil.
+func (acc *Accumulator) TermGapFrac() []float32 {
+	if acc.term == nil {
+		return nil
+	}
+	frac := make([]float32, acc.ncol)
+	for i, n := range acc.term {
+		frac[i] = float32(n) / float32(acc.nseq)
+	}
+	return frac
+}
+
+// Profile makes a Profile from the counts so far. More sequences can
+// be added afterwards and do not change it.
+func (acc *Accumulator) Profile(gapsAreChar bool) (*Profile, error) {
+	if acc.nseq == 0 {
+		return nil, ErrNoSequences
+	}
+	grp := &acc.grp
+	used := acc.SymUsed()
+	grp.clear()
+	grp.symUsed, grp.usedKnwn = used, true
+	grp.mapsyms()
+	if err := grp.SetNSym(grp.nSym); err != nil {
+		return nil, err
+	}
+	nrow := len(grp.revmap)
+	p := &Profile{
+		counts:      matrix.NewIMatrix2d(nrow, acc.ncol),
+		weights:     matrix.NewFMatrix2d(nrow, acc.ncol),
+		freqs:       matrix.NewFMatrix2d(nrow, acc.ncol),
+		revmap:      append([]uint8(nil), grp.revmap...),
+		mapping:     grp.mapping,
+		remap:       acc.remap,
+		nseq:        acc.nseq,
+		ncol:        acc.ncol,
+		logbase:     grp.GetLogBase(gapsAreChar),
+		gapsAreChar: gapsAreChar,
+	}
+	for irow, c := range grp.revmap {
+		if acc.bySym[c] != nil {
+			copy(p.counts.Mat[irow], acc.bySym[c])
+		}
+	}
+	var term []int32
+	if grp.termWt != nil {
+		term = acc.term
+	}
+	weigh(p.counts.Mat, term, grp.mapping[GapChar], grp.termWt, p.weights.Mat)
+	p.finish()
+	return p, nil
+}
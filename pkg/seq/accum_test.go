@@ -0,0 +1,96 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// TestAccumulator streams a file into an Accumulator and reads it into
+// a SeqGrp. With case folding, terminal gap weights, an alphabet and
+// either gap policy, the profiles must be exactly the same.
+func TestAccumulator(t *testing.T) {
+	const s = "> s1\n--acgT\n> s2\nAC-GTT\n> s3\nACNGt-\n> s4\nA.CGTT\n"
+	fname, err := common.WrtTemp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+	for _, gapsAreChar := range []bool{true, false} {
+		for _, alpha := range []*Alphabet{nil, DNAAlphabet()} {
+			seqgrp, err := Readfile(fname, &Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			seqgrp.Upper()
+			acc := NewAccumulator()
+			acc.FoldCase()
+			if alpha != nil {
+				seqgrp.SetAlphabet(alpha)
+				acc.SetAlphabet(alpha)
+			}
+			seqgrp.SetTermGapWeight(0.5)
+			acc.SetTermGapWeight(0.5)
+			if err := StreamFile(fname, &Options{}, acc.Add); err != nil {
+				t.Fatal(err)
+			}
+			want := newProfile(t, seqgrp, gapsAreChar)
+			got, err := acc.Profile(gapsAreChar)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got.Symbols()) != string(want.Symbols()) || !sameProfile(got, want) {
+				t.Fatalf("gapsAreChar %v alphabet %v profiles differ", gapsAreChar, alpha != nil)
+			}
+			if got.LogBase() != want.LogBase() || !sliceEql(got.GapFrac(), want.GapFrac()) {
+				t.Fatal("log base or gap fractions differ")
+			}
+			if !sliceEql(acc.TermGapFrac(), seqgrp.TermGapFrac()) {
+				t.Fatal("terminal gap fractions differ")
+			}
+		}
+	}
+}
+
+// TestAccumulatorErrors has a short fourth sequence. The reader finds
+// it, unless told lengths may differ, when the Accumulator does. A2M
+// cannot be streamed, so it is refused.
+func TestAccumulatorErrors(t *testing.T) {
+	const s = "> s1\nACGT\n> s2\nACGT\n> s3\nACGT\n> s4\nACG\n"
+	fname, err := common.WrtTemp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+	for _, s_opts := range []*Options{{}, {DiffLenSeq: true}} {
+		var lenErr *LengthMismatchError
+		err := StreamFile(fname, s_opts, NewAccumulator().Add)
+		if !errors.As(err, &lenErr) || lenErr.Record != 3 || lenErr.Want != 4 {
+			t.Fatalf("DiffLenSeq %v wanted length error at record 3, got %v", s_opts.DiffLenSeq, err)
+		}
+	}
+	a2mName, err := common.WrtTemp("> s1\nAC.gT\n> s2\nACa.T\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(a2mName)
+	for _, s_opts := range []*Options{{A2M: true}, {InFormat: "a2m"}} {
+		if err := StreamFile(a2mName, s_opts, NewAccumulator().Add); err == nil {
+			t.Fatalf("A2M %v, InFormat %q, streaming should fail", s_opts.A2M, s_opts.InFormat)
+		}
+	}
+	acc := NewAccumulator()
+	if _, err := acc.Profile(false); err != ErrNoSequences {
+		t.Fatal("empty accumulator should give ErrNoSequences, got", err)
+	}
+	acc.Add("s1", []byte("ACGT"))
+	acc.SetNSym(3)
+	if _, err := acc.Profile(false); err == nil {
+		t.Fatal("3 symbols should be too few for ACGT")
+	}
+}
@@ -0,0 +1,201 @@
+// 17 Oct 2026
+// Alphabets. An alphabet says which symbols are valid residues or bases,
+// which are gaps and which are ambiguity codes (X in proteins, N or R in
+// DNA). It also has a remapping table which is applied to every symbol
+// before it is counted, so lower case can become upper case, "." can
+// become "-" and selenocysteine (U) can be counted as cysteine (C).
+// Without an alphabet, a SeqGrp counts symbols exactly as they are in
+// the file and guesses the type, as it always did.
+
+package seq
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// Alphabet describes the symbols in one kind of sequence. Use
+// ProteinAlphabet, DNAAlphabet or RNAAlphabet, or NewAlphabet for
+// anything else. Each call gives a new copy, so it can be changed
+// with SetRemap without upsetting anyone else.
+type Alphabet struct {
+	name    string
+	stype   SeqType
+	symbols string     // canonical symbols, what the entropy is over
+	gaps    string     // gap symbols, all mapped to GapChar
+	ambig   string     // ambiguity codes
+	remap   [256]uint8 // remap[c] is what c is counted as
+}
+
+const (
+	protSymbols = "ACDEFGHIKLMNPQRSTVWY"
+	dnaSymbols  = "ACGT"
+	rnaSymbols  = "ACGU"
+	gapSymbols  = "-.~"
+	protAmbig   = "BJXZ"
+	ntideAmbig  = "NRYKMSWBDHV"
+)
+
+// NewAlphabet makes an alphabet from strings of symbols, gaps and
+// ambiguity codes. Nothing is remapped, except that every gap symbol
+// is counted as GapChar.
+func NewAlphabet(name string, stype SeqType, symbols, gaps, ambig string) *Alphabet {
+	a := &Alphabet{name: name, stype: stype, symbols: symbols, gaps: gaps, ambig: ambig}
+	for i := range a.remap {
+		a.remap[i] = uint8(i)
+	}
+	for i := 0; i < len(gaps); i++ {
+		a.remap[gaps[i]] = GapChar
+	}
+	return a
+}
+
+// foldCase remaps every lower case letter to whatever its upper case
+// letter is mapped to.
+func (a *Alphabet) foldCase() *Alphabet {
+	const diff = 'a' - 'A'
+	for c := 'A'; c <= 'Z'; c++ {
+		a.remap[c+diff] = a.remap[c]
+	}
+	return a
+}
+
+// ProteinAlphabet has the 20 amino acids. Lower case is counted as upper
+// case, selenocysteine (U) as cysteine and pyrrolysine (O) as lysine.
+func ProteinAlphabet() *Alphabet {
+	a := NewAlphabet("protein", Protein, protSymbols, gapSymbols, protAmbig)
+	a.SetRemap('U', 'C')
+	a.SetRemap('O', 'K')
+	return a.foldCase()
+}
+
+// DNAAlphabet has ACGT. Lower case is counted as upper case.
+func DNAAlphabet() *Alphabet {
+	return NewAlphabet("dna", DNA, dnaSymbols, gapSymbols, ntideAmbig).foldCase()
+}
+
+// RNAAlphabet has ACGU. Lower case is counted as upper case.
+func RNAAlphabet() *Alphabet {
+	return NewAlphabet("rna", RNA, rnaSymbols, gapSymbols, ntideAmbig).foldCase()
+}
+
+// AlphabetByName returns one of the built in alphabets, "protein",
+// "dna" or "rna".
+func AlphabetByName(name string) (*Alphabet, error) {
+	switch strings.ToLower(name) {
+	case "protein":
+		return ProteinAlphabet(), nil
+	case "dna":
+		return DNAAlphabet(), nil
+	case "rna":
+		return RNAAlphabet(), nil
+	}
+	return nil, fmt.Errorf("unknown alphabet \"%s\", know about protein, dna and rna", name)
+}
+
+// alphabetForType gives the built in alphabet for a guessed type, or
+// nil if there is none.
+func alphabetForType(stype SeqType) *Alphabet {
+	switch stype {
+	case Protein:
+		return ProteinAlphabet()
+	case DNA, Ntide:
+		return DNAAlphabet()
+	case RNA:
+		return RNAAlphabet()
+	}
+	return nil
+}
+
+// SetRemap says symbol from should be counted as to. Lower case
+// versions are not changed, so call it for both if need be.
+func (a *Alphabet) SetRemap(from, to byte) { a.remap[from] = a.remap[to] }
+
+// Map returns what c is counted as.
+func (a *Alphabet) Map(c byte) byte { return a.remap[c] }
+
+// Name returns the alphabet's name.
+func (a *Alphabet) Name() string { return a.name }
+
+// Type returns the sequence type the alphabet is for.
+func (a *Alphabet) Type() SeqType { return a.stype }
+
+// Symbols returns the canonical symbols, not gaps or ambiguity codes.
+func (a *Alphabet) Symbols() string { return a.symbols }
+
+// Size is the number of canonical symbols. It is the base for
+// logarithms in entropy calculations.
+func (a *Alphabet) Size() int { return len(a.symbols) }
+
+// IsSymbol says if c, after remapping, is a canonical symbol.
+func (a *Alphabet) IsSymbol(c byte) bool {
+	return strings.IndexByte(a.symbols, a.remap[c]) != -1
+}
+
+// IsGap says if c is a gap symbol.
+func (a *Alphabet) IsGap(c byte) bool { return a.remap[c] == GapChar }
+
+// IsAmbig says if c, after remapping, is an ambiguity code.
+func (a *Alphabet) IsAmbig(c byte) bool {
+	return strings.IndexByte(a.ambig, a.remap[c]) != -1
+}
+
+// Valid says if c is a symbol, gap or ambiguity code.
+func (a *Alphabet) Valid(c byte) bool {
+	return a.IsSymbol(c) || a.IsGap(c) || a.IsAmbig(c)
+}
+
+// identity is the remapping table when there is no alphabet.
+var identity = func() (t [256]uint8) {
+	for i := range t {
+		t[i] = uint8(i)
+	}
+	return
+}()
+
+// SetAlphabet tells the seqgrp what kind of sequences it has. From then
+// on, symbols are remapped before they are counted, GetType returns the
+// alphabet's type and GetLogBase uses its size. Any counts already
+// calculated are thrown away.
+func (seqgrp *SeqGrp) SetAlphabet(a *Alphabet) {
+	seqgrp.clear()
+	seqgrp.alpha = a
+}
+
+// Alphabet returns the alphabet given to SetAlphabet or, if there was
+// none, the built in alphabet for the guessed type. It returns nil if
+// the type cannot be guessed.
+func (seqgrp *SeqGrp) Alphabet() *Alphabet {
+	if seqgrp.alpha != nil {
+		return seqgrp.alpha
+	}
+	return alphabetForType(seqgrp.GetType())
+}
+
+// remapTable returns the table symbols go through before counting.
+func (seqgrp *SeqGrp) remapTable() *[256]uint8 {
+	if seqgrp.alpha == nil {
+		return &identity
+	}
+	return &seqgrp.alpha.remap
+}
+
+// CheckAlphabet looks for symbols which are not in the alphabet. The
+// error is a ParseError for the first one.
+func (seqgrp *SeqGrp) CheckAlphabet() error {
+	a := seqgrp.Alphabet()
+	if a == nil {
+		return nil
+	}
+	for i, ss := range seqgrp.seqs {
+		for j, c := range ss.seq {
+			if !a.Valid(c) {
+				err := fmt.Errorf("symbol %q is not in the %s alphabet", c, a.name)
+				return &ParseError{Record: i, Header: ss.cmmt, Column: j + 1, Err: err}
+			}
+		}
+	}
+	return nil
+}
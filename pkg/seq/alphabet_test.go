@@ -0,0 +1,118 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestAlphabet checks the remapping and classification of symbols.
+func TestAlphabet(t *testing.T) {
+	prot := ProteinAlphabet()
+	maps := []struct{ from, to byte }{
+		{'a', 'A'}, {'U', 'C'}, {'u', 'C'}, {'O', 'K'}, {'.', '-'}, {'~', '-'}, {'x', 'X'},
+	}
+	for _, m := range maps {
+		if got := prot.Map(m.from); got != m.to {
+			t.Errorf("protein maps %c to %c, wanted %c", m.from, got, m.to)
+		}
+	}
+	if !prot.IsAmbig('X') || prot.IsSymbol('X') || !prot.IsSymbol('u') || prot.Valid('1') {
+		t.Error("protein symbol classes wrong")
+	}
+	dna := DNAAlphabet()
+	if dna.Size() != 4 || !dna.IsAmbig('n') || dna.IsSymbol('U') {
+		t.Error("dna symbol classes wrong")
+	}
+	if _, err := AlphabetByName("klingon"); err == nil {
+		t.Error("unknown alphabet should fail")
+	}
+	prot.SetRemap('B', 'D') // must not change the next copy
+	if ProteinAlphabet().Map('B') != 'B' {
+		t.Error("alphabets are shared")
+	}
+}
+
+// TestSetAlphabet has lower case and "." which only count as upper case
+// and gaps with an alphabet. Column 0 is then all A, so the entropy is 0.
+// Column 2 is two C and one G.
+func TestSetAlphabet(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"Aac", "a.G", "AAc"})
+	if seqgrp.GetLogBase(false) == 4 {
+		t.Fatal("without an alphabet, lower case should look like extra symbols")
+	}
+	seqgrp.SetAlphabet(DNAAlphabet())
+	if seqgrp.GetType() != DNA || seqgrp.GetLogBase(true) != 5 {
+		t.Fatal("dna alphabet type or log base wrong")
+	}
+	entropy := make([]float32, 3)
+	seqgrp.Entropy(false, entropy)
+	if entropy[0] != 0 {
+		t.Fatal("column of A and a should have zero entropy, got", entropy[0])
+	}
+	want := float32(-(2.0/3*math.Log(2.0/3) + 1.0/3*math.Log(1.0/3)) / math.Log(4))
+	if d := entropy[2] - want; d > 1e-5 || d < -1e-5 {
+		t.Fatal("column 2 entropy wanted", want, "got", entropy[2])
+	}
+	gf := gapFrac(t, seqgrp)
+	if gf[1] < 0.33 || gf[1] > 0.34 {
+		t.Fatal("\".\" should count as a gap, gap fraction", gf[1])
+	}
+}
+
+// TestAmbigLogBase has a DNA column with every base and N the same
+// number of times. N is counted in a row of its own, so the log base
+// has to be 5, or the entropy would be more than one.
+func TestAmbigLogBase(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AA", "CA", "GA", "TA", "NA"})
+	seqgrp.SetAlphabet(DNAAlphabet())
+	if b := seqgrp.GetLogBase(false); b != 5 {
+		t.Fatal("log base wanted 5, got", b)
+	}
+	if b := seqgrp.GetLogBase(true); b != 6 {
+		t.Fatal("log base with gaps wanted 6, got", b)
+	}
+	entropy := make([]float32, 2)
+	seqgrp.Entropy(false, entropy)
+	if entropy[0] > 1.00001 || entropy[0] < 0.99999 || entropy[1] != 0 {
+		t.Fatal("entropy wanted 1 and 0, got", entropy)
+	}
+	if b := Str2SeqGrp([]string{"AC", "GT"}).GetLogBase(false); b != 4 {
+		t.Fatal("no ambiguity codes, log base wanted 4, got", b)
+	}
+}
+
+// TestCheckAlphabet finds a digit in the second sequence.
+func TestCheckAlphabet(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"ACDE", "AC1E"})
+	seqgrp.SetAlphabet(ProteinAlphabet())
+	var parseErr *ParseError
+	if err := seqgrp.CheckAlphabet(); !errors.As(err, &parseErr) || parseErr.Record != 1 || parseErr.Column != 3 {
+		t.Fatal("wanted error at sequence 1 column 3, got", err)
+	}
+}
+
+// TestSetNSym has DNA with N and X, which looks like protein. Saying
+// there are 4 symbols fixes the log base. 3 is too few.
+func TestSetNSym(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"ACGTN", "acgtX"})
+	if seqgrp.GetType() != Protein {
+		t.Fatal("test is broken, N should make this look like protein")
+	}
+	if err := seqgrp.SetNSym(4); err != nil {
+		t.Fatal(err)
+	}
+	if b := seqgrp.GetLogBase(false); b != 4 {
+		t.Fatal("log base wanted 4, got", b)
+	}
+	if b := seqgrp.GetLogBase(true); b != 5 {
+		t.Fatal("log base with gaps wanted 5, got", b)
+	}
+	if err := seqgrp.SetNSym(3); err == nil {
+		t.Fatal("3 symbols should be too few")
+	}
+}
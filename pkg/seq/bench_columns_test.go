@@ -0,0 +1,75 @@
+// 17 Oct 2026
+// Per-site counting from rows and from the column-major copy, on a
+// deep alignment and a long one. The Cols versions do not include the
+// time for Transpose, which has its own benchmark, since one copy is
+// used for many calculations. The Par versions count from rows with one
+// and four goroutines, which only helps with more than one processor.
+// go test -bench Usage -benchmem
+package seq_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// randAln makes nseq random protein sequences of length ncol with
+// some gaps.
+func randAln(nseq, ncol int) *seq.SeqGrp {
+	const syms = "ACDEFGHIKLMNPQRSTVWY----"
+	rng := rand.New(rand.NewSource(1))
+	ss := make([]string, nseq)
+	b := make([]byte, ncol)
+	for i := range ss {
+		for j := range b {
+			b[j] = syms[rng.Intn(len(syms))]
+		}
+		ss[i] = string(b)
+	}
+	return seq.Str2SeqGrp(ss)
+}
+
+func benchmarkUsage(b *testing.B, nseq, ncol int, transpose bool) {
+	seqgrp := randAln(nseq, ncol)
+	if transpose {
+		if _, err := seqgrp.Transpose(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		seqgrp.Clear()
+		seqgrp.UsageSite()
+	}
+}
+
+func benchmarkTranspose(b *testing.B, nseq, ncol int) {
+	seqgrp := randAln(nseq, ncol)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := seq.NewColumns(seqgrp); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUsageRowsDeep(b *testing.B) { benchmarkUsage(b, 100000, 300, false) }
+func BenchmarkUsageColsDeep(b *testing.B) { benchmarkUsage(b, 100000, 300, true) }
+func BenchmarkUsageRowsLong(b *testing.B) { benchmarkUsage(b, 5000, 5000, false) }
+func BenchmarkUsageColsLong(b *testing.B) { benchmarkUsage(b, 5000, 5000, true) }
+func BenchmarkTransposeDeep(b *testing.B) { benchmarkTranspose(b, 100000, 300) }
+func BenchmarkTransposeLong(b *testing.B) { benchmarkTranspose(b, 5000, 5000) }
+
+func benchmarkUsagePar(b *testing.B, nseq, ncol, nworker int) {
+	seqgrp := randAln(nseq, ncol)
+	seqgrp.SetNWorker(nworker)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		seqgrp.Clear()
+		seqgrp.UsageSite()
+	}
+}
+
+func BenchmarkUsagePar1Deep(b *testing.B) { benchmarkUsagePar(b, 100000, 300, 1) }
+func BenchmarkUsagePar4Deep(b *testing.B) { benchmarkUsagePar(b, 100000, 300, 4) }
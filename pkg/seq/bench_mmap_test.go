@@ -0,0 +1,62 @@
+// 17 Oct 2026
+// Compare reading with and without mapping the file. writeTmpSeqFile
+// from bench_readfasta_test.go has white space in every line, so every
+// sequence has to be cleaned. writeCleanSeqFile has each sequence on
+// one line, so the mapped version does not copy anything.
+// The parallel versions split the file and parse with several goroutines.
+// go test -bench 'Mmap|Par' -benchmem
+package seq_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+func writeCleanSeqFile() (*os.File, error) {
+	fp, err := os.CreateTemp(".", "del_me")
+	if err != nil {
+		return nil, err
+	}
+	nseq := 214651
+	s := strings.Repeat("aaaaaaaaaaaaa", 27)
+	for i := 0; i < nseq; i++ {
+		fmt.Fprintln(fp, "> seq", i)
+		fmt.Fprintln(fp, s)
+	}
+	return fp, nil
+}
+
+func benchmarkMmap(b *testing.B, mkfile func() (*os.File, error), s_opts *seq.Options) {
+	fp, err := mkfile()
+	if err != nil {
+		b.Fatal("program bug")
+	}
+	fp.Close()
+	b.Cleanup(func() { os.Remove(fp.Name()) })
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		seqgrp, err := seq.Readfile(fp.Name(), s_opts)
+		if err != nil {
+			b.Fatal("benchmark broke reading sequences", err)
+		}
+		seqgrp.Close()
+	}
+}
+
+var (
+	mmapOpts = &seq.Options{Mmap: true}
+	plain    = &seq.Options{}
+	par4     = &seq.Options{NWorker: 4}
+	par4Mmap = &seq.Options{NWorker: 4, Mmap: true}
+)
+
+func BenchmarkMmapClean(b *testing.B)     { benchmarkMmap(b, writeCleanSeqFile, mmapOpts) }
+func BenchmarkNoMmapClean(b *testing.B)   { benchmarkMmap(b, writeCleanSeqFile, plain) }
+func BenchmarkMmapWhite(b *testing.B)     { benchmarkMmap(b, writeTmpSeqFile, mmapOpts) }
+func BenchmarkNoMmapWhite(b *testing.B)   { benchmarkMmap(b, writeTmpSeqFile, plain) }
+func BenchmarkParallelWhite(b *testing.B) { benchmarkMmap(b, writeTmpSeqFile, par4) }
+func BenchmarkParMmapWhite(b *testing.B)  { benchmarkMmap(b, writeTmpSeqFile, par4Mmap) }
@@ -0,0 +1,311 @@
+// 17 Oct 2026
+// Clustal (.aln) format. A file looks like
+//
+//	CLUSTAL W (1.83) multiple sequence alignment
+//
+//	seq1      ACDEFG-HIK 9
+//	seq2      ACDEYGLHIK 10
+//	          **** * ***
+//
+//	seq1      ...
+//
+// Each block has one line per sequence, optionally followed by a running
+// residue count. The conservation line under each block begins with white
+// space. We ignore it on reading and calculate it on writing.
+// MUSCLE and PROBCONS write the same thing, but put their own name on
+// the first line, so we accept those too, after any blank lines.
+
+package seq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// clustalMagic are the words a clustal file may start with.
+var clustalMagic = []string{"CLUSTAL", "MUSCLE", "PROBCONS"}
+
+// isClustalHdr says if line is the first line of a clustal file.
+func isClustalHdr(line []byte) bool {
+	for _, magic := range clustalMagic {
+		if bytes.HasPrefix(line, []byte(magic)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Residue groups used by clustal for the conservation line. If all the
+// residues in a column fall in one strong group, the column gets a ":".
+// If they fall in a weak group, it gets a ".".
+var (
+	strongGroups = []string{
+		"STA", "NEQK", "NHQK", "NDEQ", "QHRK", "MILV", "MILF", "HY", "FYW"}
+	weakGroups = []string{
+		"CSA", "ATV", "SAG", "STNK", "STPA", "SGND", "SNDEQK", "NDEQHK",
+		"NEQHRK", "FVLIM", "HFY"}
+)
+
+// seqName returns a name for sequence number i which can be used in
+// formats that do not allow spaces in names. It is the first word of
+// the comment, or something made up if there is no comment.
+func seqName(s seq, i int) string {
+	if f := strings.Fields(s.cmmt); len(f) > 0 {
+		return f[0]
+	}
+	return fmt.Sprint("s", i)
+}
+
+// uniqueNames returns an error if two sequences would be written with
+// the same name. Phylogenetics programs refuse files like that, or
+// worse, quietly merge the sequences.
+func uniqueNames(names []string, format string) error {
+	seen := make(map[string]int, len(names))
+	for i, name := range names {
+		if j, ok := seen[name]; ok {
+			return fmt.Errorf("%s: sequences %d and %d both have the name \"%s\"", format, j, i, name)
+		}
+		seen[name] = i
+	}
+	return nil
+}
+
+// nameWidth returns the length of the longest name, for padding.
+func nameWidth(names []string) int {
+	n := 0
+	for _, name := range names {
+		if len(name) > n {
+			n = len(name)
+		}
+	}
+	return n
+}
+
+// alignedOnly is the error from writers whose rows have to be aligned,
+// when asked to remove gaps.
+const alignedOnly = "%s needs aligned rows, so gaps cannot be removed when writing"
+
+// alnSeqs returns the sequences to be written in an alignment format
+// and their names. Empty (cleared) sequences are dropped. The rest
+// have to be the same length, unless rmvGaps is set. Then we return
+// copies without gaps, which may be any length, and drop any which
+// were only gaps.
+func alnSeqs(seqgrp *SeqGrp, rmvGaps bool) (names []string, seqs []seq, err error) {
+	const bustLen = "writing alignment, seq %d has length %d, wanted %d"
+	for i, ss := range seqgrp.seqs {
+		if rmvGaps {
+			ss.seq = bytes.ReplaceAll(ss.seq, []byte{common.GapChar}, nil)
+		}
+		if ss.Empty() {
+			continue
+		}
+		if !rmvGaps && len(seqs) > 0 && ss.Len() != seqs[0].Len() {
+			return nil, nil, fmt.Errorf(bustLen, i, ss.Len(), seqs[0].Len())
+		}
+		names = append(names, seqName(ss, i))
+		seqs = append(seqs, ss)
+	}
+	if len(seqs) == 0 {
+		return nil, nil, errors.New("no sequences to write")
+	}
+	return names, seqs, nil
+}
+
+// blockReader collects sequences from formats where each line is a
+// name and a piece of sequence and blocks of lines are interleaved.
+type blockReader struct {
+	order  []string
+	byName map[string][]byte
+}
+
+// add appends a piece of sequence to the named sequence.
+func (b *blockReader) add(name string, piece []byte) {
+	if b.byName == nil {
+		b.byName = make(map[string][]byte)
+	}
+	if _, ok := b.byName[name]; !ok {
+		b.order = append(b.order, name)
+	}
+	b.byName[name] = append(b.byName[name], piece...)
+}
+
+// toGrp moves the sequences into a seqgrp. Any characters in gaps are
+// changed to our gap character.
+func (b *blockReader) toGrp(seqgrp *SeqGrp, gaps string) {
+	for _, name := range b.order {
+		s := b.byName[name]
+		for i, c := range s {
+			if strings.IndexByte(gaps, c) != -1 {
+				s[i] = common.GapChar
+			}
+		}
+		seqgrp.seqs = append(seqgrp.seqs, seq{cmmt: name, seq: s})
+	}
+}
+
+// ReadClustal reads a clustal format alignment into seqgrp. The options
+// work as for ReadFasta.
+func ReadClustal(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const badLine = "clustal: expected name and sequence, got \"%s\""
+	var blocks blockReader
+	scanner := newScanner(rdr)
+	started := false // have we seen anything but blank lines
+	for nline := 1; scanner.Scan(); nline++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !started {
+			started = true
+			if isClustalHdr([]byte(strings.TrimSpace(line))) {
+				continue
+			}
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue // conservation line
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			return &ParseError{Record: -1, Line: nline, Err: fmt.Errorf(badLine, line)}
+		}
+		if _, err := strconv.Atoi(f[len(f)-1]); err == nil && len(f) > 2 {
+			f = f[:len(f)-1] // drop the residue count
+		}
+		for _, piece := range f[1:] {
+			blocks.add(f[0], []byte(piece))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	blocks.toGrp(seqgrp, "")
+	return finishGrp(seqgrp, s_opts)
+}
+
+// inOneGroup says if all the residues in used are in one of the groups.
+func inOneGroup(used []byte, groups []string) bool {
+	for _, grp := range groups {
+		all := true
+		for _, c := range used {
+			if strings.IndexByte(grp, c) == -1 {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// Conservation returns the clustal conservation line for an alignment.
+// A "*" marks a column with a single residue type and no gaps. For
+// proteins, ":" and "." mark columns whose residues fall in one of
+// clustal's strong or weak groups. Anything else is a space.
+// Upper and lower case are the same. Empty (cleared) sequences are left
+// out, as WriteClustal does. It is nil if there is nothing to write or
+// the sequences are not all the same length.
+func (seqgrp *SeqGrp) Conservation() []byte {
+	_, seqs, err := alnSeqs(seqgrp, false)
+	if err != nil {
+		return nil
+	}
+	return conservation(seqs, seqgrp.remapTable(), seqgrp.GetType() == Protein)
+}
+
+// conservation does the work for Conservation on the sequences from
+// alnSeqs. Only letters, after remap, are residues, so a gap or
+// anything else in a column means it is not conserved.
+func conservation(seqs []seq, remap *[256]uint8, protein bool) []byte {
+	cons := make([]byte, seqs[0].Len())
+	var used []byte
+	for icol := range cons {
+		used = used[:0]
+		residues := true
+		for _, ss := range seqs {
+			c := remap[ss.seq[icol]]
+			if 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			if c < 'A' || c > 'Z' {
+				residues = false
+				break
+			}
+			if bytes.IndexByte(used, c) == -1 {
+				used = append(used, c)
+			}
+		}
+		switch {
+		case !residues:
+			cons[icol] = ' '
+		case len(used) == 1:
+			cons[icol] = '*'
+		case protein && inOneGroup(used, strongGroups):
+			cons[icol] = ':'
+		case protein && inOneGroup(used, weakGroups):
+			cons[icol] = '.'
+		default:
+			cons[icol] = ' '
+		}
+	}
+	return cons
+}
+
+// WriteClustal writes an alignment in clustal format, with the
+// conservation line under each block. Names are the first word of each
+// comment. Empty (cleared) sequences are not written. With RmvGapsWrt,
+// the sequences are written without gaps, so they are no longer
+// aligned. Each one stops when it runs out and there is no
+// conservation line.
+func WriteClustal(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error {
+	const header = "CLUSTAL multiple sequence alignment\n\n"
+	const c_per_line = 60
+	names, seqs, err := alnSeqs(seqgrp, s_opts.RmvGapsWrt)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	width := nameWidth(names) + 6
+	var cons []byte
+	alnLen := 0
+	if s_opts.RmvGapsWrt {
+		for _, ss := range seqs {
+			alnLen = max(alnLen, ss.Len())
+		}
+	} else {
+		cons = conservation(seqs, seqgrp.remapTable(), seqgrp.GetType() == Protein)
+		alnLen = len(cons)
+	}
+	count := make([]int, len(seqs))
+	bw.WriteString(header)
+	for start := 0; start < alnLen; start += c_per_line {
+		end := start + c_per_line
+		bw.WriteByte('\n')
+		for i, ss := range seqs {
+			if start >= ss.Len() {
+				continue
+			}
+			piece := ss.seq[start:min(end, ss.Len())]
+			count[i] += len(piece) - bytes.Count(piece, []byte{common.GapChar})
+			fmt.Fprintf(bw, "%-*s%s %d\n", width, names[i], piece, count[i])
+		}
+		if cons != nil {
+			fmt.Fprintf(bw, "%-*s%s\n", width, "", cons[start:min(end, alnLen)])
+		}
+	}
+	return bw.Flush()
+}
+
+// isClustal looks at the start of a file and says if it is clustal
+// format. Blank lines at the start do not matter.
+func isClustal(head []byte) bool {
+	return isClustalHdr(bytes.TrimLeft(head, " \t\r\n"))
+}
@@ -0,0 +1,187 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+var aln1 = `CLUSTAL W (1.83) multiple sequence alignment
+
+
+s1              ACDEFG-HIK 9
+s2              ACDEYGLHIK 10
+                **** * ***
+
+s1              LM 11
+s2              VM 12
+                :*
+`
+
+// TestClustalRead reads a two-block file
+func TestClustalRead(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadClustal(strings.NewReader(aln1), &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ACDEFG-HIKLM", "ACDEYGLHIKVM"}
+	for i, ss := range seqgrp.SeqSlc() {
+		if s := string(ss.GetSeq()); s != want[i] {
+			t.Fatalf("clustal seq %d wanted %s got %s", i, want[i], s)
+		}
+	}
+	if c := seqgrp.SeqSlc()[1].Cmmt(); c != "s2" {
+		t.Fatal("clustal name got", c)
+	}
+}
+
+// TestConservation checks the *:. line against a hand-made example.
+// Then the first sequence is cleared, so it is not written and does not
+// count, and a column of "#" is not conserved, since "#" is no residue.
+func TestConservation(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AASLW-S", "ACTIW-T", "aGAVWDN"})
+	if got := string(seqgrp.Conservation()); got != "* ::* ." {
+		t.Fatalf("conservation got \"%s\"", got)
+	}
+	seqgrp = Str2SeqGrp([]string{"GGGG", "A#AC", "a#AC"})
+	seqgrp.SeqSlc()[0].Clear()
+	if got := string(seqgrp.Conservation()); got != "* **" {
+		t.Fatalf("with a cleared sequence, conservation got \"%s\"", got)
+	}
+	var b bytes.Buffer
+	if err := WriteClustal(&b, seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "* **\n") {
+		t.Fatal("conservation line missing\n", b.String())
+	}
+}
+
+// TestClustalRoundTrip writes and reads back clustal and msf files,
+// with and without gaps.
+func TestClustalRoundTrip(t *testing.T) {
+	type rdFn func(*strings.Reader, *SeqGrp, *Options) error
+	type wrtFn func(*bytes.Buffer, *SeqGrp, *Options) error
+	long := strings.Repeat("ACDEFGHIKL", 13)
+	ss := []string{long, strings.Replace(long, "E", "-", -1), strings.ToLower(long)}
+	formats := []struct {
+		name string
+		rd   rdFn
+		wrt  wrtFn
+	}{
+		{"clustal",
+			func(r *strings.Reader, g *SeqGrp, o *Options) error { return ReadClustal(r, g, o) },
+			func(w *bytes.Buffer, g *SeqGrp, o *Options) error { return WriteClustal(w, g, o) }},
+		{"msf",
+			func(r *strings.Reader, g *SeqGrp, o *Options) error { return ReadMSF(r, g, o) },
+			func(w *bytes.Buffer, g *SeqGrp, o *Options) error { return WriteMSF(w, g, o) }},
+	}
+	for _, f := range formats {
+		for _, rmv := range []bool{false, true} {
+			var b bytes.Buffer
+			if err := f.wrt(&b, Str2SeqGrp(ss, "name"), &Options{RmvGapsWrt: rmv}); err != nil {
+				t.Fatal(f.name, "writing", err)
+			}
+			var seqgrp SeqGrp
+			if err := f.rd(strings.NewReader(b.String()), &seqgrp, &Options{DiffLenSeq: rmv}); err != nil {
+				t.Fatal(f.name, "reading back", err, "\n", b.String())
+			}
+			if seqgrp.NSeq() != len(ss) {
+				t.Fatal(f.name, "got", seqgrp.NSeq(), "seqs")
+			}
+			for i, s := range seqgrp.SeqSlc() {
+				want := ss[i]
+				if rmv {
+					want = strings.ReplaceAll(want, "-", "")
+				}
+				if string(s.GetSeq()) != want {
+					t.Fatal(f.name, rmv, "seq", i, "wanted\n", want, "got\n", string(s.GetSeq()))
+				}
+				if s.Cmmt() != "name"+string(rune('0'+i)) {
+					t.Fatal(f.name, "name got", s.Cmmt())
+				}
+			}
+		}
+	}
+}
+
+// TestClustalHeaders reads files from MUSCLE and PROBCONS, which use
+// their own names on the first line, and one with blank lines first.
+func TestClustalHeaders(t *testing.T) {
+	_, rest, _ := strings.Cut(aln1, "\n")
+	for _, s := range []string{
+		"MUSCLE (3.8) multiple sequence alignment\n" + rest,
+		"PROBCONS version 1.12 multiple sequence alignment\n" + rest,
+		"\n\n" + aln1,
+	} {
+		var seqgrp SeqGrp
+		if err := ReadClustal(strings.NewReader(s), &seqgrp, &Options{}); err != nil {
+			t.Fatal(err, "\n", s)
+		}
+		tmpname, err := wrtTmp(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpname)
+		fromFile, err := Readfile(tmpname, &Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range []*SeqGrp{&seqgrp, fromFile} {
+			if g.NSeq() != 2 || string(g.SeqSlc()[1].GetSeq()) != "ACDEYGLHIKVM" {
+				t.Fatal("misread\n", s)
+			}
+		}
+	}
+}
+
+// TestMSFCheck checks the GCG checksum for a trivial case, 1*'A' + 2*'C'
+func TestMSFCheck(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteMSF(&b, Str2SeqGrp([]string{"ac"}), &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "Check:   199") {
+		t.Fatal("wrong checksum in\n", b.String())
+	}
+}
+
+// TestMSFReadfile checks that Readfile recognises msf and clustal files
+// and turns msf gaps into "-".
+func TestMSFReadfile(t *testing.T) {
+	msf := `PileUp
+
+ MSF: 6  Type: P  Check:  1234  ..
+
+ Name: a  Len:  6  Check:  1  Weight:  1.00
+ Name: b  Len:  6  Check:  1  Weight:  1.00
+
+//
+
+     1     6
+a   AC.DE~
+b   ACFDEG
+`
+	for _, s := range []string{msf, aln1} {
+		tmpname, err := wrtTmp(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpname)
+		seqgrp, err := Readfile(tmpname, &Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seqgrp.NSeq() != 2 {
+			t.Fatal("Readfile got", seqgrp.NSeq(), "seqs")
+		}
+		if s == msf && string(seqgrp.SeqSlc()[0].GetSeq()) != "AC-DE-" {
+			t.Fatal("msf gaps not converted", string(seqgrp.SeqSlc()[0].GetSeq()))
+		}
+	}
+}
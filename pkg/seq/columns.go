@@ -0,0 +1,161 @@
+// 17 Oct 2026
+// Column-major storage. Sequences are kept as rows, which is right for
+// reading and writing, but every per-site calculation wants columns.
+// Walking rows and adding into counts.Mat[symbol][site] jumps all over
+// the counts matrix and, for alignments with 100k sequences, that is
+// most of the time. Transpose makes a copy of the alignment with the
+// columns next to each other. UsageSite (and so Profiles and gap
+// fractions) then counts one column at a time into a small table,
+// unless there are quality or sequence weights.
+// The copy costs as much memory as the alignment, so it is optional.
+
+package seq
+
+import (
+	"iter"
+	"sync"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// Columns is an alignment stored column by column. It is a copy, so
+// changing a SeqGrp's sequences does not change it.
+type Columns struct {
+	data []byte // column i is data[i*nseq : (i+1)*nseq]
+	nseq int
+	ncol int
+}
+
+// transposeBlk is the size of the square blocks we copy in. Both the
+// rows we read and the columns we write fit in the cache.
+const transposeBlk = 64
+
+// NewColumns copies the sequences of seqgrp into column-major order.
+// The sequences must all be the same length.
+func NewColumns(seqgrp *SeqGrp) (*Columns, error) {
+	if err := check_lengths(seqgrp.seqs); err != nil {
+		return nil, err
+	}
+	nseq := len(seqgrp.seqs)
+	if nseq == 0 {
+		return nil, ErrNoSequences
+	}
+	ncol := seqgrp.GetLen()
+	c := &Columns{data: make([]byte, nseq*ncol), nseq: nseq, ncol: ncol}
+	for s0 := 0; s0 < nseq; s0 += transposeBlk {
+		s1 := min(s0+transposeBlk, nseq)
+		for c0 := 0; c0 < ncol; c0 += transposeBlk {
+			c1 := min(c0+transposeBlk, ncol)
+			for is := s0; is < s1; is++ {
+				row := seqgrp.seqs[is].seq
+				for ic := c0; ic < c1; ic++ {
+					c.data[ic*nseq+is] = row[ic]
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// NSeq is the number of sequences, the length of each column.
+func (c *Columns) NSeq() int { return c.nseq }
+
+// Len is the number of columns.
+func (c *Columns) Len() int { return c.ncol }
+
+// Col returns column i. It points into the Columns, so do not change it.
+func (c *Columns) Col(i int) []byte { return c.data[i*c.nseq : (i+1)*c.nseq] }
+
+// All iterates over the columns, giving the index and the column.
+//
+//	for i, col := range cols.All() { ... }
+func (c *Columns) All() iter.Seq2[int, []byte] {
+	return func(yield func(int, []byte) bool) {
+		for i := 0; i < c.ncol; i++ {
+			if !yield(i, c.Col(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns a mask with true for each column where keep is true.
+// It is for SeqGrp.KeepCols.
+func (c *Columns) Filter(keep func(col []byte) bool) []bool {
+	mask := make([]bool, c.ncol)
+	for i, col := range c.All() {
+		mask[i] = keep(col)
+	}
+	return mask
+}
+
+// GapFrac returns the fraction of GapChar in each column. Unlike
+// SeqGrp.GapFrac, there is no remapping or weighting.
+func (c *Columns) GapFrac() []float32 {
+	frac := make([]float32, c.ncol)
+	for i, col := range c.All() {
+		n := 0
+		for _, b := range col {
+			if b == GapChar {
+				n++
+			}
+		}
+		frac[i] = float32(n) / float32(c.nseq)
+	}
+	return frac
+}
+
+// Transpose makes a column-major copy of the sequences and keeps it, so
+// UsageSite and NewProfile count from it. It is not updated if the
+// sequences are changed, except by Upper and KeepCols, so call
+// Transpose again, or DropColumns, after changing them yourself.
+func (seqgrp *SeqGrp) Transpose() (*Columns, error) {
+	cols, err := NewColumns(seqgrp)
+	if err != nil {
+		return nil, err
+	}
+	seqgrp.cols = cols
+	return cols, nil
+}
+
+// Columns returns the copy from Transpose, or nil.
+func (seqgrp *SeqGrp) Columns() *Columns { return seqgrp.cols }
+
+// DropColumns throws away the copy from Transpose.
+func (seqgrp *SeqGrp) DropColumns() { seqgrp.cols = nil }
+
+// tallyCols is tally from the column-major copy. Each column is counted
+// into a table on the stack, then added to the rows. With more than one
+// worker, each takes a range of columns, so they never write to the
+// same place and nothing has to be merged.
+func (seqgrp *SeqGrp) tallyCols(raw [][]int32) {
+	remap := seqgrp.remapTable()
+	count := func(c0, c1 int) {
+		for icol := c0; icol < c1; icol++ {
+			var hist [256]int32
+			for _, c := range seqgrp.cols.Col(icol) {
+				hist[c]++
+			}
+			for c, n := range hist {
+				if n != 0 {
+					raw[seqgrp.mapping[remap[c]]][icol] += n
+				}
+			}
+		}
+	}
+	ncol := seqgrp.cols.ncol
+	nworker := min(seqgrp.nWorker, ncol)
+	if nworker <= 1 {
+		count(0, ncol)
+		return
+	}
+	var wg sync.WaitGroup
+	for iw := 0; iw < nworker; iw++ {
+		wg.Add(1)
+		go func(c0, c1 int) {
+			defer wg.Done()
+			count(c0, c1)
+		}(iw*ncol/nworker, (iw+1)*ncol/nworker)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,78 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestColumns checks the transposed copy and its gap fractions.
+func TestColumns(t *testing.T) {
+	cols, err := NewColumns(Str2SeqGrp([]string{"AC-", "GT-", "GG-", "A--"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols.NSeq() != 4 || cols.Len() != 3 || string(cols.Col(0)) != "AGGA" || string(cols.Col(1)) != "CTG-" {
+		t.Fatal("columns wrong")
+	}
+	if g := cols.GapFrac(); g[0] != 0 || g[1] != 0.25 || g[2] != 1 {
+		t.Fatal("gap fractions wrong", g)
+	}
+	var lenErr *LengthMismatchError
+	if _, err := NewColumns(Str2SeqGrp([]string{"AC", "A"})); !errors.As(err, &lenErr) {
+		t.Fatal("wanted LengthMismatchError, got", err)
+	}
+}
+
+// TestTallyCols checks counting from columns gives exactly what
+// counting from rows gives.
+func TestTallyCols(t *testing.T) {
+	seqgrp := randAln(300, 70)
+	rows := newProfile(t, seqgrp, false)
+	if _, err := seqgrp.Transpose(); err != nil {
+		t.Fatal(err)
+	}
+	cols := newProfile(t, seqgrp, false)
+	for _, c := range rows.Symbols() {
+		for i := 0; i < rows.Len(); i++ {
+			if rows.Count(c, i) != cols.Count(c, i) || rows.Freq(c, i) != cols.Freq(c, i) {
+				t.Fatalf("symbol %c column %d differs", c, i)
+			}
+		}
+	}
+}
+
+// TestFilterCols drops the all-gap columns, found from the column-major
+// copy, which must shrink with the sequences.
+func TestFilterCols(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"A-c-", "G-t-", "a-T-"})
+	cols, err := seqgrp.Transpose()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := cols.Filter(func(col []byte) bool {
+		for _, c := range col {
+			if c != '-' {
+				return true
+			}
+		}
+		return false
+	})
+	seqgrp.KeepCols(mask)
+	seqgrp.Upper()
+	want := []string{"AC", "GT", "AT"}
+	for i, ss := range seqgrp.SeqSlc() {
+		if string(ss.GetSeq()) != want[i] {
+			t.Fatalf("seq %d got %s want %s", i, ss.GetSeq(), want[i])
+		}
+	}
+	if cols.Len() != 2 || string(cols.Col(1)) != "CTT" {
+		t.Fatal("column-major copy not kept up to date")
+	}
+	if p := newProfile(t, seqgrp, false); p.Count('T', 1) != 2 {
+		t.Fatal("counts from columns wrong")
+	}
+}
@@ -0,0 +1,83 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestReadGzip writes fasta and stockholm files compressed and checks
+// Readfile unpacks them. Fasta cannot be counted first on a
+// decompression stream, so this checks the blocks which grow.
+func TestReadGzip(t *testing.T) {
+	fasta := "> s1\nACDEFGHIKL\n> s2\nACDEF-HIKL\n> s3\nacdefghikl\n"
+	stk := "# STOCKHOLM 1.0\ns1 ACDEFGHIKL\ns2 ACDEF-HIKL\ns3 acdefghikl\n//\n"
+	want := []string{"ACDEFGHIKL", "ACDEF-HIKL", "acdefghikl"}
+	for _, s := range []string{fasta, stk} {
+		var b bytes.Buffer
+		zw := gzip.NewWriter(&b)
+		zw.Write([]byte(s))
+		zw.Close()
+		tmpname, err := wrtTmp(b.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpname)
+		seqgrp, err := Readfile(tmpname, &Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seqgrp.NSeq() != len(want) {
+			t.Fatal("wanted", len(want), "seqs, got", seqgrp.NSeq())
+		}
+		for i, ss := range seqgrp.SeqSlc() {
+			if string(ss.GetSeq()) != want[i] {
+				t.Fatal("seq", i, "wanted", want[i], "got", string(ss.GetSeq()))
+			}
+		}
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	*bytes.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += n
+	return n, err
+}
+
+// TestGzipOnce checks that seekable, compressed fasta is only
+// decompressed once. It used to be unpacked once to count the
+// sequences and again to read them.
+func TestGzipOnce(t *testing.T) {
+	var fasta strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&fasta, "> s%d\n%s\n", i, strings.Repeat("ACDEFGHIKL", 20))
+	}
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(fasta.String()))
+	zw.Close()
+	rdr := &countingReader{Reader: bytes.NewReader(b.Bytes())}
+	var seqgrp SeqGrp
+	if err := ReadAny(rdr, &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if seqgrp.NSeq() != 500 {
+		t.Fatal("wanted 500 seqs, got", seqgrp.NSeq())
+	}
+	if rdr.n > b.Len()+512 { // the first bytes are read twice, to sniff
+		t.Fatal("read", rdr.n, "bytes of", b.Len())
+	}
+}
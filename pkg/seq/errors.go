@@ -0,0 +1,77 @@
+// 17 Oct 2026
+// Errors from reading. A caller can pick them apart with errors.Is and
+// errors.As to find out which sequence and which line were to blame.
+// Readfile wraps them with the file name, which errors.As looks through.
+
+package seq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoSequences means the input was read, but had no sequences in it.
+var ErrNoSequences = errors.New("No sequences found")
+
+// errA2MStream is from ReadA2M, when called by StreamFile.
+var errA2MStream = errors.New("a2m/a3m needs the whole alignment, so it cannot be streamed")
+
+// These are inside ParseErrors.
+var (
+	errZeroLen = errors.New("zero length sequence")
+	errNoCmmt  = errors.New("fasta input should start with \">\"")
+)
+
+// ParseError says where in the input something went wrong. Record is
+// the number of the sequence, counting from zero, or -1 if the problem
+// is not in one sequence. Line and Column count from one. Zero means we
+// do not know. Header may be empty if the record had not got that far.
+type ParseError struct {
+	Record int
+	Header string
+	Line   int
+	Column int
+	Err    error
+}
+
+// where describes a position in the input, for error messages.
+func where(record int, header string, line, column int) string {
+	var parts []string
+	if line > 0 {
+		parts = append(parts, fmt.Sprintf("line %d", line))
+	}
+	if column > 0 {
+		parts = append(parts, fmt.Sprintf("column %d", column))
+	}
+	s := strings.Join(parts, " ")
+	if record >= 0 {
+		s = strings.TrimSpace(fmt.Sprintf("%s record %d", s, record))
+	}
+	if header != "" {
+		s = fmt.Sprintf("%s \"%s\"", s, trimStr(header, 40))
+	}
+	return s
+}
+
+func (e *ParseError) Error() string {
+	return where(e.Record, e.Header, e.Line, e.Column) + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// LengthMismatchError is for a sequence whose length is not the same
+// as the first one, when the sequences should be aligned. The positions
+// are as for ParseError.
+type LengthMismatchError struct {
+	Record int
+	Header string
+	Line   int
+	Want   int // length of the first sequence
+	Got    int
+}
+
+func (e *LengthMismatchError) Error() string {
+	const bustLen = "seqs not same length, wanted %d, got %d"
+	return where(e.Record, e.Header, e.Line, 0) + ": " + fmt.Sprintf(bustLen, e.Want, e.Got)
+}
@@ -0,0 +1,123 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// TestLengthMismatch has the third sequence one short. Its header is on
+// line 6.
+func TestLengthMismatch(t *testing.T) {
+	const s = "> s1\nACDE\nFG\n> s2\nACDEFG\n> s3 short\nACD\nEF\n> s4\nACDEFG\n"
+	var seqgrp SeqGrp
+	err := ReadFasta(strings.NewReader(s), &seqgrp, &Options{})
+	var lenErr *LengthMismatchError
+	if !errors.As(err, &lenErr) {
+		t.Fatalf("wanted LengthMismatchError, got %v", err)
+	}
+	if lenErr.Record != 2 || lenErr.Line != 6 || lenErr.Want != 6 || lenErr.Got != 5 {
+		t.Fatalf("wrong position or lengths %+v", *lenErr)
+	}
+	if lenErr.Header != " s3 short" {
+		t.Fatalf("header got \"%s\"", lenErr.Header)
+	}
+}
+
+// TestErrorsThroughReadfile checks errors.As and errors.Is see through
+// the file name Readfile adds.
+func TestErrorsThroughReadfile(t *testing.T) {
+	files := []struct {
+		name, s string
+		s_opts  Options
+		want    error
+	}{
+		{"zero length", "> s1\nAC\n> s2\n\n> s3\nAC\n", Options{DiffLenSeq: true}, &ParseError{}},
+		{"length", "> s1\nAC\n> s2\nACD\n", Options{}, &LengthMismatchError{}},
+		{"parallel length", "> s1\nAC\n> s2\nACD\n", Options{NWorker: 2}, &LengthMismatchError{}},
+		{"nothing", "\n", Options{}, ErrNoSequences},
+	}
+	for _, f := range files {
+		fname, err := common.WrtTemp(f.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(fname)
+		_, err = Readfile(fname, &f.s_opts)
+		var parseErr *ParseError
+		var lenErr *LengthMismatchError
+		switch f.want.(type) {
+		case *ParseError:
+			if !errors.As(err, &parseErr) || parseErr.Record != 1 {
+				t.Errorf("%s: wanted ParseError for record 1, got %v", f.name, err)
+			}
+		case *LengthMismatchError:
+			if !errors.As(err, &lenErr) || lenErr.Record != 1 {
+				t.Errorf("%s: wanted LengthMismatchError for record 1, got %v", f.name, err)
+			}
+		default:
+			if !errors.Is(err, f.want) {
+				t.Errorf("%s: wanted %v, got %v", f.name, f.want, err)
+			}
+		}
+	}
+}
+
+// TestFastqError checks the column of a bad quality character.
+func TestFastqError(t *testing.T) {
+	const s = "@r1\nACGT\n+\nIIII\n@r2\nACGT\n+\nII\x01I\n"
+	var seqgrp SeqGrp
+	err := ReadFastq(strings.NewReader(s), &seqgrp, &Options{})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatal("wanted ParseError, got", err)
+	}
+	if parseErr.Record != 1 || parseErr.Header != "r2" || parseErr.Line != 8 || parseErr.Column != 3 {
+		t.Fatalf("wrong position %+v", *parseErr)
+	}
+}
+
+// TestErrorLines puts a short sequence deep enough in the file that the
+// parallel reader finds it in a later piece. Every way of reading fasta
+// should give the line of its header. Two blank lines and three lines
+// per record put record 13 on line 42. The fastq read which is only gaps
+// is caught after reading, but still has its line.
+func TestErrorLines(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("\n\n")
+	for i := 0; i < 20; i++ {
+		if i == 13 {
+			fmt.Fprintf(&b, "> s%d\nACGT\nA\n", i)
+		} else {
+			fmt.Fprintf(&b, "> s%d\nACGT\nAC\n", i)
+		}
+	}
+	fname, err := common.WrtTemp(b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+	for _, s_opts := range []Options{{}, {NWorker: 4}, {Mmap: true}, {Mmap: true, NWorker: 3}} {
+		_, err := Readfile(fname, &s_opts)
+		var lenErr *LengthMismatchError
+		if !errors.As(err, &lenErr) || lenErr.Record != 13 || lenErr.Line != 42 {
+			t.Fatalf("NWorker %d, Mmap %v wanted record 13 on line 42, got %v",
+				s_opts.NWorker, s_opts.Mmap, err)
+		}
+	}
+
+	const fq = "@r1\nACGT\n+\nIIII\n@r2\n----\n+\nIIII\n"
+	var seqgrp SeqGrp
+	err = ReadFastq(strings.NewReader(fq), &seqgrp, &Options{RmvGapsRd: true})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Record != 1 || parseErr.Line != 5 {
+		t.Fatal("wanted zero length record 1 on line 5, got", err)
+	}
+}
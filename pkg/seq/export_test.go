@@ -23,3 +23,12 @@ func (seqgrp *SeqGrp) PrintFreqs(format string) {
 var SetFastaRdSize = setFastaRdSize
 
 func (seqgrp *SeqGrp) Clear() { seqgrp.clear() }
+
+var SplitChunks = splitChunks
+
+var ReadAny = readAny
+
+func (seqgrp *SeqGrp) Profile(gapsAreChar bool) (*Profile, error) { return seqgrp.profile(gapsAreChar) }
+
+// DropSeqs keeps only the first n sequences, behind the SeqGrp's back.
+func (seqgrp *SeqGrp) DropSeqs(n int) { seqgrp.seqs = seqgrp.seqs[:n] }
@@ -0,0 +1,279 @@
+// 17 Oct 2026
+// Indexed fasta files, compatible with samtools faidx. The index
+// (file.fa.fai) has one tab-separated line per sequence
+//
+//	name  length  offset  linebases  linewidth
+//
+// name is the first word of the comment, length is the number of
+// residues, offset is where the first residue starts in the file,
+// linebases is the number of residues per line and linewidth is the
+// number of bytes per line, including the end of line. From this, we
+// can work out where any residue is and read just the bit we want.
+// For this to work, all lines in a sequence must be the same length,
+// except the last one.
+
+package seq
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/unzip"
+	"github.com/andrew-torda/seq_compat/pkg/white"
+)
+
+// FaiExt is added to the name of a fasta file to get its index.
+const FaiExt = ".fai"
+
+// FaiEntry is one line of a .fai index.
+type FaiEntry struct {
+	Name      string
+	Length    int64 // number of residues
+	Offset    int64 // byte offset of first residue
+	LineBases int   // residues per line
+	LineWidth int   // bytes per line, including newline
+}
+
+// Fai is an index for a fasta file.
+type Fai struct {
+	entries []FaiEntry
+	byName  map[string]int
+}
+
+// add puts an entry in the index, checking the name is not repeated.
+func (fai *Fai) add(e FaiEntry) error {
+	if fai.byName == nil {
+		fai.byName = make(map[string]int)
+	}
+	if _, ok := fai.byName[e.Name]; ok {
+		return fmt.Errorf("fai: sequence name %s is repeated", e.Name)
+	}
+	fai.byName[e.Name] = len(fai.entries)
+	fai.entries = append(fai.entries, e)
+	return nil
+}
+
+// Entries returns the index entries in the order of the fasta file.
+func (fai *Fai) Entries() []FaiEntry { return fai.entries }
+
+// Entry returns the index entry for a sequence name.
+func (fai *Fai) Entry(name string) (FaiEntry, bool) {
+	i, ok := fai.byName[name]
+	if !ok {
+		return FaiEntry{}, false
+	}
+	return fai.entries[i], true
+}
+
+// BuildFai reads through a fasta file and builds the index. It does not
+// keep any sequences.
+func BuildFai(rdr io.Reader) (*Fai, error) {
+	const (
+		badLine   = "fai: different line length in sequence %s"
+		noHeader  = "fai: residues before first \">\" at offset %d"
+		badHeader = "fai: sequence with no name at offset %d"
+	)
+	fai := new(Fai)
+	br := bufio.NewReaderSize(rdr, 64*1024)
+	var cur *FaiEntry
+	var pos int64  // where we are in the file
+	var short bool // seen a line shorter than linebases, must be the last
+	finish := func() error {
+		if cur == nil {
+			return nil
+		}
+		return fai.add(*cur)
+	}
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull { // long line, get the rest
+			full := append([]byte(nil), line...)
+			for err == bufio.ErrBufferFull {
+				line, err = br.ReadSlice('\n')
+				full = append(full, line...)
+			}
+			line = full
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		width := len(line)
+		nbase := len(bytes.TrimRight(line, "\r\n"))
+		unended := line[width-1] != '\n' // last line, with no end of line
+		switch {
+		case line[0] == cmmt_char:
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			f := strings.Fields(string(line[1:]))
+			if len(f) == 0 {
+				return nil, fmt.Errorf(badHeader, pos)
+			}
+			cur = &FaiEntry{Name: f[0], Offset: pos + int64(width)}
+			short = false
+		case cur == nil:
+			if nbase != 0 {
+				return nil, fmt.Errorf(noHeader, pos)
+			}
+		case nbase == 0:
+			short = true // blank line, only allowed at the end
+		default:
+			if cur.LineBases == 0 {
+				cur.LineBases, cur.LineWidth = nbase, width
+				if unended { // As samtools, count a newline
+					cur.LineWidth++
+				}
+			} else if short || nbase > cur.LineBases {
+				return nil, fmt.Errorf(badLine, cur.Name)
+			} else if !unended && width-nbase != cur.LineWidth-cur.LineBases {
+				return nil, fmt.Errorf(badLine, cur.Name)
+			}
+			if nbase < cur.LineBases {
+				short = true
+			}
+			cur.Length += int64(nbase)
+		}
+		pos += int64(width)
+		if err == io.EOF {
+			break
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(fai.entries) == 0 {
+		return nil, ErrNoSequences
+	}
+	return fai, nil
+}
+
+// Write writes the index in samtools format.
+func (fai *Fai) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range fai.entries {
+		fmt.Fprintf(bw, "%s\t%d\t%d\t%d\t%d\n", e.Name, e.Length, e.Offset, e.LineBases, e.LineWidth)
+	}
+	return bw.Flush()
+}
+
+// ReadFai reads an index written by Write or by samtools.
+func ReadFai(rdr io.Reader) (*Fai, error) {
+	const badLine = "fai: line %d should have five fields, got \"%s\""
+	fai := new(Fai)
+	scanner := newScanner(rdr)
+	for n := 1; scanner.Scan(); n++ {
+		f := strings.Split(scanner.Text(), "\t")
+		if len(f) < 5 {
+			return nil, fmt.Errorf(badLine, n, scanner.Text())
+		}
+		var nums [4]int64
+		for i := range nums {
+			var err error
+			if nums[i], err = strconv.ParseInt(f[i+1], 10, 64); err != nil {
+				return nil, fmt.Errorf(badLine, n, scanner.Text())
+			}
+		}
+		e := FaiEntry{Name: f[0], Length: nums[0], Offset: nums[1],
+			LineBases: int(nums[2]), LineWidth: int(nums[3])}
+		if err := fai.add(e); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return fai, nil
+}
+
+// IndexedFasta is an open fasta file with its index.
+type IndexedFasta struct {
+	fp  *os.File
+	fai *Fai
+}
+
+// OpenIndexed opens a fasta file and reads its index from fname.fai.
+// If there is no index, it is built and we try to write it, but do not
+// complain if we cannot (read-only directory). Compressed files cannot
+// be indexed.
+func OpenIndexed(fname string) (*IndexedFasta, error) {
+	fp, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	var head [unzip.MagicLen]byte
+	n, _ := io.ReadFull(fp, head[:])
+	if unzip.Sniff(head[:n]) != unzip.None {
+		fp.Close()
+		return nil, fmt.Errorf("%s is compressed, cannot use an index", fname)
+	}
+	ix := &IndexedFasta{fp: fp}
+	if faiFp, err := os.Open(fname + FaiExt); err == nil {
+		ix.fai, err = ReadFai(faiFp)
+		faiFp.Close()
+		if err != nil {
+			fp.Close()
+			return nil, fmt.Errorf("%s%s: %w", fname, FaiExt, err)
+		}
+		return ix, nil
+	}
+	if _, err := fp.Seek(0, io.SeekStart); err != nil {
+		fp.Close()
+		return nil, err
+	}
+	if ix.fai, err = BuildFai(fp); err != nil {
+		fp.Close()
+		return nil, fmt.Errorf("indexing %s: %w", fname, err)
+	}
+	if faiFp, err := os.Create(fname + FaiExt); err == nil {
+		if ix.fai.Write(faiFp) != nil || faiFp.Close() != nil {
+			os.Remove(fname + FaiExt)
+		}
+	}
+	return ix, nil
+}
+
+// Fai returns the index.
+func (ix *IndexedFasta) Fai() *Fai { return ix.fai }
+
+// Close closes the fasta file.
+func (ix *IndexedFasta) Close() error { return ix.fp.Close() }
+
+// Fetch reads residues start to end of the sequence called name.
+// Like RangeStart and RangeEnd in Options, start and end count from
+// zero and end is included. An end less than zero means the end of the
+// sequence. Only the bytes needed are read from the file.
+func (ix *IndexedFasta) Fetch(name string, start, end int64) ([]byte, error) {
+	const invalidRange = "invalid seq range %d to %d for %s, length is only %d"
+	e, ok := ix.fai.Entry(name)
+	if !ok {
+		return nil, fmt.Errorf("sequence %s not in index", name)
+	}
+	if end < 0 {
+		end = e.Length - 1
+	}
+	if start < 0 || end >= e.Length || start > end {
+		return nil, fmt.Errorf(invalidRange, start, end, name, e.Length)
+	}
+	where := func(p int64) int64 {
+		lb, lw := int64(e.LineBases), int64(e.LineWidth)
+		return e.Offset + (p/lb)*lw + p%lb
+	}
+	from, to := where(start), where(end)+1
+	b := make([]byte, to-from)
+	if _, err := ix.fp.ReadAt(b, from); err != nil {
+		return nil, err
+	}
+	white.Remove(&b)
+	if int64(len(b)) != end-start+1 {
+		return nil, fmt.Errorf("index does not match file for %s", name)
+	}
+	return b, nil
+}
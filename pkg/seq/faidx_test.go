@@ -0,0 +1,105 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+const faiSeqs = ">s1 desc\nACGTA\nCGTAC\nGT\n>s2\nAAAA\nCC\n>s3\n\n"
+
+// wanted index, worked out by hand, as samtools would write it
+const faiWant = "s1\t12\t9\t5\t6\ns2\t6\t28\t4\t5\ns3\t0\t40\t0\t0\n"
+
+// TestBuildFai checks the index and that we complain about lines of
+// different lengths. The last line need not end in a newline, which
+// samtools counts as if it were there.
+func TestBuildFai(t *testing.T) {
+	fai, err := BuildFai(strings.NewReader(faiSeqs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := fai.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != faiWant {
+		t.Fatalf("wanted\n%sgot\n%s", faiWant, b.String())
+	}
+	back, err := ReadFai(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := back.Entry("s2"); !ok || e.Offset != 28 {
+		t.Fatal("reading index back, got", e)
+	}
+	for _, u := range []struct{ s, want string }{
+		{">s1\nACGT\nAC", "s1\t6\t4\t4\t5\n"},
+		{">s1\r\nACGT\r\nAC", "s1\t6\t5\t4\t6\n"},
+		{">s1\nACGT", "s1\t4\t4\t4\t5\n"},
+	} {
+		fai, err := BuildFai(strings.NewReader(u.s))
+		if err != nil {
+			t.Fatalf("%q: %v", u.s, err)
+		}
+		b.Reset()
+		fai.Write(&b)
+		if b.String() != u.want {
+			t.Fatalf("%q wanted %q got %q", u.s, u.want, b.String())
+		}
+	}
+	for _, s := range []string{">a\nACG\nT\nACG\n", ">a\nAC\nACG\n", ">a\nACG\n>a\nACG\n", ">a\nACG\nACGT"} {
+		if _, err := BuildFai(strings.NewReader(s)); err == nil {
+			t.Fatalf("should fail on %q", s)
+		}
+	}
+}
+
+// TestFetch opens a file, which builds the index, and then opens it
+// again, which reads the index.
+func TestFetch(t *testing.T) {
+	data := []struct {
+		name       string
+		start, end int64
+		want       string
+	}{
+		{"s1", 3, 7, "TACGT"},
+		{"s1", 0, -1, "ACGTACGTACGT"},
+		{"s2", 0, -1, "AAAACC"},
+		{"s2", 5, 5, "C"},
+		{"crlf", 2, 3, "GT"},
+	}
+	tmpname, err := wrtTmp(faiSeqs + ">crlf\r\nACG\r\nT\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpname)
+	defer os.Remove(tmpname + FaiExt)
+	for _, pass := range []string{"build", "read"} {
+		ix, err := OpenIndexed(tmpname)
+		if err != nil {
+			t.Fatal(pass, err)
+		}
+		for _, d := range data {
+			got, err := ix.Fetch(d.name, d.start, d.end)
+			if err != nil {
+				t.Fatal(pass, err)
+			}
+			if string(got) != d.want {
+				t.Fatal(pass, d.name, "wanted", d.want, "got", string(got))
+			}
+		}
+		if _, err := ix.Fetch("s2", 0, 6); err == nil {
+			t.Fatal("should fail fetching past the end")
+		}
+		if _, err := ix.Fetch("nothere", 0, -1); err == nil {
+			t.Fatal("should fail fetching missing sequence")
+		}
+		ix.Close()
+	}
+}
@@ -0,0 +1,134 @@
+// 17 Oct 2026
+// FASTQ format. Each record looks like
+//
+//	@read1 some comment
+//	ACGT-ACGT
+//	+
+//	IIII!IIII
+//
+// The quality line has one character per base, Phred score + 33. We
+// keep the Phred scores (not the characters) next to each sequence.
+// Sequence and quality may be wrapped over several lines, so we read
+// quality lines until there are as many as there are bases.
+// The qualities can be used to weight counts in UsageSite.
+
+package seq
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	fastqChar   = '@'
+	phredOffset = 33 // Sanger and Illumina 1.8+
+	maxPhred    = 126 - phredOffset
+)
+
+// ReadFastq reads a FASTQ file into seqgrp. The options work as for
+// ReadFasta. Qualities follow their bases through gap removal and
+// ranges.
+func ReadFastq(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const (
+		noAt     = "fastq: line should start with \"@\", got \"%s\""
+		noPlus   = "fastq: no \"+\" line"
+		badQual  = "fastq: bad quality character %q"
+		shortQul = "fastq: %d bases, but %d qualities"
+	)
+	scanner := newScanner(rdr)
+	nline := 0
+	next := func() (string, bool) {
+		for scanner.Scan() {
+			nline++
+			if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+				return line, true
+			}
+		}
+		return "", false
+	}
+	line, ok := next()
+	for ok {
+		record := len(seqgrp.seqs)
+		if line[0] != fastqChar {
+			return &ParseError{Record: record, Line: nline, Err: fmt.Errorf(noAt, line)}
+		}
+		cmmt, hdrLine := line[1:], nline
+		var s []byte
+		for line, ok = next(); ok && line[0] != '+'; line, ok = next() {
+			s = append(s, strings.TrimSpace(line)...)
+		}
+		if !ok {
+			return &ParseError{Record: record, Header: cmmt, Line: hdrLine, Err: errors.New(noPlus)}
+		}
+		qual := make([]byte, 0, len(s))
+		for len(qual) < len(s) {
+			if line, ok = next(); !ok {
+				break
+			}
+			for i := 0; i < len(line); i++ {
+				c := line[i]
+				if c < phredOffset || c-phredOffset > maxPhred {
+					return &ParseError{Record: record, Header: cmmt, Line: nline,
+						Column: i + 1, Err: fmt.Errorf(badQual, c)}
+				}
+				qual = append(qual, c-phredOffset)
+			}
+		}
+		if len(qual) != len(s) {
+			return &ParseError{Record: record, Header: cmmt, Line: hdrLine,
+				Err: fmt.Errorf(shortQul, len(s), len(qual))}
+		}
+		seqgrp.seqs = append(seqgrp.seqs, seq{cmmt: cmmt, seq: s, qual: qual, line: hdrLine})
+		line, ok = next()
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return finishGrp(seqgrp, s_opts)
+}
+
+// isFastq looks at the start of a file and says if it is FASTQ.
+func isFastq(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && head[0] == fastqChar
+}
+
+// QualWeight says how base qualities are used when counting symbols
+// in UsageSite.
+type QualWeight byte
+
+const (
+	QualIgnore QualWeight = iota // Every base counts one
+	QualProb                     // A base counts the probability it is right
+	QualCutoff                   // Bases below a quality cutoff are not counted
+)
+
+// SetQualWeight sets how UsageSite uses base qualities. With QualProb,
+// a base with Phred score q counts 1 - 10^(-q/10). With QualCutoff,
+// bases with a score less than cutoff are not counted at all. Gaps have
+// no quality and always count one. Sequences without qualities (from
+// fasta files) always count one. Any counts already calculated are
+// thrown away.
+func (seqgrp *SeqGrp) SetQualWeight(w QualWeight, cutoff int) {
+	seqgrp.clear()
+	seqgrp.qualWt = nil
+	if w == QualIgnore {
+		return
+	}
+	var weight [256]float32
+	for q := range weight {
+		switch w {
+		case QualProb:
+			weight[q] = float32(1 - math.Pow(10, -float64(q)/10))
+		case QualCutoff:
+			if q >= cutoff {
+				weight[q] = 1
+			}
+		}
+	}
+	seqgrp.qualWt = &weight
+}
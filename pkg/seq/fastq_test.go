@@ -0,0 +1,105 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// Read three has a bad first base. "I" is Phred 40, "#" is 2, "5" is 20
+const fastq = `@r1 first read
+AC-GT
++
+IIIII
+@r2
+AC-G
+T
++r2
+III
+II
+@r3
+TC-GT
++
+#III5
+`
+
+// TestReadFastq reads wrapped records and checks the qualities follow
+// the bases when gaps are removed.
+func TestReadFastq(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadFastq(strings.NewReader(fastq), &seqgrp, &Options{RmvGapsRd: true}); err != nil {
+		t.Fatal(err)
+	}
+	ss := seqgrp.SeqSlc()
+	if len(ss) != 3 || ss[0].Cmmt() != "r1 first read" {
+		t.Fatal("wanted 3 seqs, got", len(ss))
+	}
+	if s := string(ss[2].GetSeq()); s != "TCGT" {
+		t.Fatal("wanted TCGT got", s)
+	}
+	want := []byte{2, 40, 40, 20}
+	if q := ss[2].Qual(); string(q) != string(want) {
+		t.Fatal("wanted", want, "got", q)
+	}
+	for _, bad := range []string{
+		"@r1\nACGT\n+\nIII\n",     // short quality
+		"@r1\nACGT\n",             // no +
+		"r1\nACGT\n+\nIIII\n",     // no @
+		"@r1\nACGT\n+\nII\x1fI\n", // bad character
+	} {
+		if err := ReadFastq(strings.NewReader(bad), &seqgrp, &Options{}); err == nil {
+			t.Fatalf("should fail on %q", bad)
+		}
+	}
+}
+
+// TestQualWeight reads the file through Readfile and counts the first
+// column with each kind of weighting.
+func TestQualWeight(t *testing.T) {
+	tmpname, err := wrtTmp(fastq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpname)
+	seqgrp, err := Readfile(tmpname, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seqgrp.SeqSlc()[0].Qual() == nil {
+		t.Fatal("not read as fastq")
+	}
+	good := 1 - math.Pow(10, -4)
+	bad := 1 - math.Pow(10, -0.2)
+	data := []struct {
+		w      QualWeight
+		cutoff int
+		a, t   float64
+	}{
+		{QualIgnore, 0, 2, 1},
+		{QualCutoff, 20, 2, 0},
+		{QualProb, 0, 2 * good, bad},
+	}
+	for _, d := range data {
+		seqgrp.SetQualWeight(d.w, d.cutoff)
+		counts := seqgrp.GetCounts().Mat
+		a := counts[seqgrp.GetMapping('A')][0]
+		tt := counts[seqgrp.GetMapping('T')][0]
+		if math.Abs(float64(a)-d.a) > 1e-5 || math.Abs(float64(tt)-d.t) > 1e-5 {
+			t.Fatal("weight", d.w, "wanted", d.a, d.t, "got", a, tt)
+		}
+		if g := counts[seqgrp.GetMapping('-')][2]; g != 3 {
+			t.Fatal("gaps should count one each, got", g)
+		}
+	}
+	seqgrp.SetQualWeight(QualCutoff, 20)
+	entropy := make([]float32, seqgrp.GetLen())
+	seqgrp.Entropy(false, entropy)
+	if entropy[0] != 0 {
+		t.Fatal("with the bad base dropped, entropy should be zero, got", entropy[0])
+	}
+}
@@ -0,0 +1,106 @@
+// 17 Oct 2026
+// ReadFasta applies the reading options (gap removal, ranges, length
+// checks) as it goes. The readers for other formats collect a whole
+// alignment first and then call finishGrp to get the same behaviour.
+
+package seq
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+	"github.com/andrew-torda/seq_compat/pkg/white"
+)
+
+// keepNonGap removes the entries from an annotation track at the
+// positions where s has a gap. s and annot should be the same length.
+func keepNonGap(annot, s []byte) []byte {
+	if len(annot) != len(s) {
+		return annot
+	}
+	t := annot[:0]
+	for i, c := range s {
+		if c != common.GapChar {
+			t = append(t, annot[i])
+		}
+	}
+	return t
+}
+
+// toGapChar turns any of the characters in gapChars into GapChar, so
+// "." and "~" from other aligners are counted as gaps.
+func toGapChar(b []byte, gapChars string) {
+	for i, c := range b {
+		if strings.IndexByte(gapChars, c) != -1 {
+			b[i] = common.GapChar
+		}
+	}
+}
+
+// finishGrp takes a seqgrp which has just been filled by one of the
+// readers and applies the options, as ReadFasta would. Extra gap
+// characters become GapChar, gaps are removed (with the matching
+// places in any per-residue annotation), zero length sequences are
+// caught, lengths are checked and finally, a range is cut out of every
+// sequence and column annotation.
+func finishGrp(seqgrp *SeqGrp, s_opts *Options) error {
+	const invalidRange = "invalid seq range %d to %d, length is only %d"
+	if err := checkBroken(s_opts); err != nil {
+		return err
+	}
+	if len(seqgrp.seqs) == 0 {
+		return ErrNoSequences
+	}
+	seqgrp.parseHeaders(s_opts.Templates)
+	for i := range seqgrp.seqs {
+		ss := &seqgrp.seqs[i]
+		if s_opts.GapChars != "" {
+			toGapChar(ss.seq, s_opts.GapChars)
+		}
+		if s_opts.RmvGapsRd {
+			if i < len(seqgrp.seqAnnot) {
+				for tag, annot := range seqgrp.seqAnnot[i] {
+					seqgrp.seqAnnot[i][tag] = keepNonGap(annot, ss.seq)
+				}
+			}
+			ss.qual = keepNonGap(ss.qual, ss.seq)
+			white.CharRemove(&ss.seq, common.GapChar)
+		}
+		if len(ss.seq) == 0 && !s_opts.ZeroLenOK {
+			return &ParseError{Record: i, Header: ss.cmmt, Line: ss.line, Err: errZeroLen}
+		}
+	}
+	if s_opts.DiffLenSeq {
+		return nil
+	}
+	if err := check_lengths(seqgrp.seqs); err != nil {
+		return err
+	}
+	if s_opts.RangeStart == 0 && s_opts.RangeEnd == 0 {
+		return nil
+	}
+	rs, re := s_opts.RangeStart, s_opts.RangeEnd
+	if l := seqgrp.GetLen(); rs < 0 || rs > re || re >= l {
+		return fmt.Errorf(invalidRange, rs, re, l)
+	}
+	cut := func(b []byte) []byte {
+		if len(b) <= re {
+			return b
+		}
+		return b[rs : re+1]
+	}
+	for i := range seqgrp.seqs {
+		seqgrp.seqs[i].seq = cut(seqgrp.seqs[i].seq)
+		seqgrp.seqs[i].qual = cut(seqgrp.seqs[i].qual)
+	}
+	for tag, annot := range seqgrp.colAnnot {
+		seqgrp.colAnnot[tag] = cut(annot)
+	}
+	for _, m := range seqgrp.seqAnnot {
+		for tag, annot := range m {
+			m[tag] = cut(annot)
+		}
+	}
+	return nil
+}
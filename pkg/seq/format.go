@@ -0,0 +1,173 @@
+// 17 Oct 2026
+// The format registry. Each file format has a name, a reader, a writer
+// and a function which looks at the start of a file and says if it is
+// in this format. Readfile and Writefile go through the registry, so
+// every program can read and write every format and a new format only
+// has to be registered once. The formats we have ourselves are
+// registered in init().
+
+package seq
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ReadFunc reads sequences from rdr into seqgrp.
+type ReadFunc func(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error
+
+// WriteFunc writes the sequences in seqgrp to w.
+type WriteFunc func(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error
+
+// SniffFunc looks at the first bytes of a file and says if it is in
+// some format.
+type SniffFunc func(head []byte) bool
+
+// Format describes one file format. Read, Write or Sniff may be nil if
+// we cannot read, write or recognise the format.
+type Format struct {
+	Name  string
+	Read  ReadFunc
+	Write WriteFunc
+	Sniff SniffFunc
+}
+
+var (
+	formatMu sync.RWMutex
+	formats  []*Format // in the order they are tried when sniffing
+)
+
+// fastaName is the default format for reading and writing.
+const fastaName = "fasta"
+
+func init() {
+	for _, f := range []Format{
+		{"stockholm", ReadStockholm, nil, isStockholm},
+		{"clustal", ReadClustal, WriteClustal, isClustal},
+		{"msf", ReadMSF, WriteMSF, isMSF},
+		{"nexus", ReadNexus, WriteNexus, isNexus},
+		{"phylip", ReadPhylip, WritePhylip, isPhylip},
+		{"fastq", ReadFastq, nil, isFastq},
+		{"a2m", ReadA2M, nil, nil},
+		{fastaName, ReadFasta, WriteFasta, isFasta},
+	} {
+		Register(f)
+	}
+}
+
+// Register adds a format to the registry. If there is already a format
+// with the same name, it is replaced. New formats are sniffed before
+// fasta, since fasta is what we assume if nothing else fits.
+func Register(f Format) {
+	formatMu.Lock()
+	defer formatMu.Unlock()
+	for i, old := range formats {
+		if old.Name == f.Name {
+			formats[i] = &f
+			return
+		}
+	}
+	n := len(formats)
+	if n > 0 && formats[n-1].Name == fastaName {
+		formats = append(formats[:n-1], &f, formats[n-1])
+		return
+	}
+	formats = append(formats, &f)
+}
+
+// Lookup finds a format by name.
+func Lookup(name string) (*Format, error) {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	for _, f := range formats {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown sequence format \"%s\", know about %v", name, formatNames())
+}
+
+// Sniff returns the first format which recognises head. If none does,
+// it returns fasta.
+func Sniff(head []byte) *Format {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	var fasta *Format
+	for _, f := range formats {
+		if f.Name == fastaName {
+			fasta = f
+			continue
+		}
+		if f.Sniff != nil && f.Sniff(head) {
+			return f
+		}
+	}
+	return fasta
+}
+
+// formatNames is Formats without the lock.
+func formatNames() []string {
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Formats returns the names of all the registered formats.
+func Formats() []string {
+	formatMu.RLock()
+	defer formatMu.RUnlock()
+	return formatNames()
+}
+
+// inFormat decides which format to read. The InFormat option wins, then
+// the A2M option, then whatever the start of the file looks like.
+func inFormat(head []byte, s_opts *Options) (*Format, error) {
+	switch {
+	case s_opts.InFormat != "":
+		return Lookup(s_opts.InFormat)
+	case s_opts.A2M:
+		return Lookup("a2m")
+	}
+	return Sniff(head), nil
+}
+
+// Writefile writes seqgrp to a file in the format named by
+// s_opts.OutFormat, or fasta if that is empty. A filename of "" or "-"
+// means standard output. With the DryRun option, nothing is written.
+func Writefile(fname string, seqgrp *SeqGrp, s_opts *Options) error {
+	name := s_opts.OutFormat
+	if name == "" {
+		name = fastaName
+	}
+	f, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	if f.Write == nil {
+		return fmt.Errorf("cannot write %s format", f.Name)
+	}
+	var w io.Writer
+	switch {
+	case s_opts.DryRun:
+		w = io.Discard
+	case fname == "" || fname == "-":
+		w = os.Stdout
+	default:
+		fp, err := os.Create(fname)
+		if err != nil {
+			return fmt.Errorf("Creating output sequence file: %w", err)
+		}
+		if err := f.Write(fp, seqgrp, s_opts); err != nil {
+			fp.Close()
+			return err
+		}
+		return fp.Close()
+	}
+	return f.Write(w, seqgrp, s_opts)
+}
@@ -0,0 +1,114 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestSniff checks each format we know is recognised from its start.
+func TestSniff(t *testing.T) {
+	heads := []struct{ head, want string }{
+		{"> s1\nACDE\n", "fasta"},
+		{"# STOCKHOLM 1.0\n", "stockholm"},
+		{"CLUSTAL W (1.83) multiple sequence alignment\n", "clustal"},
+		{"#NEXUS\nbegin data;\n", "nexus"},
+		{"@r1\nACGT\n+\nIIII\n", "fastq"},
+		{"something odd", "fasta"},
+	}
+	for _, h := range heads {
+		if got := Sniff([]byte(h.head)).Name; got != h.want {
+			t.Errorf("sniffing %q wanted %s got %s", h.head, h.want, got)
+		}
+	}
+	if _, err := Lookup("genbank"); err == nil {
+		t.Error("Lookup should fail on unknown format")
+	}
+}
+
+// TestRegister adds a silly format, one sequence per line, and reads
+// and writes it through Readfile and Writefile.
+func TestRegister(t *testing.T) {
+	const magic = "#LINES\n"
+	rd := func(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+		b, err := io.ReadAll(rdr)
+		if err != nil {
+			return err
+		}
+		var ss []string
+		for _, line := range strings.Split(string(b), "\n")[1:] {
+			if line != "" {
+				ss = append(ss, line)
+			}
+		}
+		*seqgrp = *Str2SeqGrp(ss)
+		return nil
+	}
+	wrt := func(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error {
+		io.WriteString(w, magic)
+		for _, s := range seqgrp.SeqSlc() {
+			w.Write(append(s.GetSeq(), '\n'))
+		}
+		return nil
+	}
+	sniff := func(head []byte) bool { return bytes.HasPrefix(head, []byte(magic)) }
+	Register(Format{Name: "lines", Read: rd, Write: wrt, Sniff: sniff})
+
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "x.lines")
+	if err := os.WriteFile(fname, []byte(magic+"ACD\nA-E\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	seqgrp, err := Readfile(fname, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := seqgrp.NSeq(); n != 2 {
+		t.Fatal("custom format wanted 2 seqs, got", n)
+	}
+	out := filepath.Join(dir, "y.fa")
+	if err := Writefile(out, seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(out)
+	if !bytes.HasPrefix(b, []byte{'>'}) {
+		t.Fatalf("default output should be fasta, got %q", b)
+	}
+	if err := Writefile(out, seqgrp, &Options{OutFormat: "lines"}); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ = os.ReadFile(out); string(b) != magic+"ACD\nA-E\n" {
+		t.Fatalf("custom output got %q", b)
+	}
+}
+
+// TestInFormat makes sure the InFormat option beats the guess and that
+// the registry's writers and readers agree.
+func TestInFormat(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"ACDEF", "AC-EF"})
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "x")
+	if err := Writefile(fname, seqgrp, &Options{OutFormat: "phylip"}); err != nil {
+		t.Fatal(err)
+	}
+	in, err := Readfile(fname, &Options{InFormat: "phylip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(in.SeqSlc()[1].GetSeq()); s != "AC-EF" {
+		t.Fatal("phylip round trip got", s)
+	}
+	if _, err := Readfile(fname, &Options{InFormat: "nonsense"}); err == nil {
+		t.Fatal("unknown InFormat should fail")
+	}
+	if err := Writefile(fname, seqgrp, &Options{OutFormat: "stockholm"}); err == nil {
+		t.Fatal("writing stockholm should fail, we have no writer")
+	}
+}
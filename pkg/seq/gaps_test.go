@@ -0,0 +1,84 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// TestGapChars reads "." and "~" as gaps, serially and in parallel.
+func TestGapChars(t *testing.T) {
+	const s = "> s1\nAC.~\n> s2\nACGT\n> s3\nA~~T\n"
+	want := []string{"AC--", "ACGT", "A--T"}
+	fname, err := common.WrtTemp(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fname)
+	for _, nworker := range []int{0, 2} {
+		seqgrp, err := Readfile(fname, &Options{GapChars: ".~", NWorker: nworker})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, ss := range seqgrp.SeqSlc() {
+			if got := string(ss.GetSeq()); got != want[i] {
+				t.Errorf("nworker %d seq %d got %s want %s", nworker, i, got, want[i])
+			}
+		}
+	}
+	var seqgrp SeqGrp
+	s_opts := &Options{GapChars: ".", RmvGapsRd: true, DiffLenSeq: true}
+	if err := ReadFasta(strings.NewReader(s), &seqgrp, s_opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(seqgrp.SeqSlc()[0].GetSeq()); got != "AC~" {
+		t.Fatal("only \".\" should be removed, got", got)
+	}
+}
+
+// TestTermGaps has a fragment missing the first two columns. In column
+// 1, the third sequence has an internal gap.
+func TestTermGaps(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"--AC", "GGAC", "G-AC"})
+	termFrac := seqgrp.TermGapFrac()
+	if termFrac[0] < 0.33 || termFrac[0] > 0.34 || termFrac[1] != termFrac[0] || termFrac[2] != 0 {
+		t.Fatal("terminal gap fractions wrong", termFrac)
+	}
+	if gf := gapFrac(t, seqgrp); gf[1] < 0.66 || gf[1] > 0.67 {
+		t.Fatal("without weights, column 1 gap fraction", gf[1])
+	}
+	seqgrp.SetTermGapWeight(0)
+	gf := gapFrac(t, seqgrp)
+	if gf[0] != 0 || gf[1] != 0.5 {
+		t.Fatal("ignoring terminal gaps, gap fractions", gf)
+	}
+	refseq := seqgrp.SeqSlc()[1].GetSeq()
+	if c := compat(t, seqgrp, refseq, false); c[1] != 0 || c[0] != 1 {
+		t.Fatal("only the reference has a residue at column 1, compat", c)
+	}
+}
+
+// TestOnlyTermGaps has a column with nothing but terminal gaps. With
+// weight zero, nothing counts there. That must not give NaN and the
+// column is taken as all gaps.
+func TestOnlyTermGaps(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"-AC", "-AC"})
+	seqgrp.SetTermGapWeight(0)
+	if g := gapFrac(t, seqgrp); g[0] != 1 || g[1] != 0 {
+		t.Fatal("gap fractions", g)
+	}
+	seqgrp.UsageFrac(false)
+	if f := seqgrp.GetCounts().Mat[seqgrp.GetMapping('-')][0]; f != 1 {
+		t.Fatal("gap frequency after UsageFrac wanted 1, got", f)
+	}
+	entropy := make([]float32, 3)
+	seqgrp.Entropy(false, entropy)
+	if entropy[0] != 0 {
+		t.Fatal("entropy of an empty column", entropy[0])
+	}
+}
@@ -0,0 +1,193 @@
+// 17 Oct 2026
+// Picking apart comment lines. UniProt headers look like
+//
+//	sp|P69905|HBA_HUMAN Hemoglobin subunit alpha OS=Homo sapiens OX=9606 GN=HBA1 PE=1 SV=2
+//
+// NCBI RefSeq and GenBank headers look like
+//
+//	NP_000549.1 hemoglobin subunit alpha [Homo sapiens]
+//	gi|4504347|ref|NP_000549.1| hemoglobin subunit alpha [Homo sapiens]
+//
+// Anything else gets the first word as accession and whatever is in
+// square brackets as organism. Users can give their own regular
+// expressions with named groups for headers we do not know about.
+
+package seq
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Header holds the fields we can find in a comment line. Fields we
+// cannot find are empty.
+type Header struct {
+	Accession string // P69905 or NP_000549.1
+	EntryName string // HBA_HUMAN, only UniProt has these
+	Organism  string // Homo sapiens
+	TaxID     string // 9606
+	Gene      string // HBA1
+}
+
+var (
+	uniprotRe = regexp.MustCompile(`^(?:sp|tr)\|([^|\s]+)\|(\S+)`)
+	// A UniProt key is two capital letters and "=", like "OS=".
+	uniprotKeyRe = regexp.MustCompile(`\s[A-Z]{2}=`)
+)
+
+// uniprotField returns the value after key ("OS=") up to the next key.
+func uniprotField(cmmt, key string) string {
+	i := strings.Index(cmmt, " "+key)
+	if i == -1 {
+		return ""
+	}
+	val := cmmt[i+len(key)+1:]
+	if loc := uniprotKeyRe.FindStringIndex(val); loc != nil {
+		val = val[:loc[0]]
+	}
+	return strings.TrimSpace(val)
+}
+
+// uniprotHeader recognises UniProt headers.
+func uniprotHeader(cmmt string) (Header, bool) {
+	m := uniprotRe.FindStringSubmatch(cmmt)
+	if m == nil {
+		return Header{}, false
+	}
+	return Header{
+		Accession: m[1],
+		EntryName: m[2],
+		Organism:  uniprotField(cmmt, "OS="),
+		TaxID:     uniprotField(cmmt, "OX="),
+		Gene:      uniprotField(cmmt, "GN="),
+	}, true
+}
+
+// ncbiHeader handles NCBI headers and anything else. The accession is
+// the first word, unless it is an old style gi|..|ref|..| word, where we
+// take the accession after the database tag. The organism is in the
+// last pair of square brackets.
+func ncbiHeader(cmmt string) Header {
+	var h Header
+	f := strings.Fields(cmmt)
+	if len(f) == 0 {
+		return h
+	}
+	h.Accession = f[0]
+	if parts := strings.Split(strings.Trim(f[0], "|"), "|"); len(parts) > 1 {
+		for i := 0; i+1 < len(parts); i++ {
+			switch parts[i] {
+			case "ref", "gb", "emb", "dbj", "pir", "prf", "pdb", "tpg":
+				h.Accession = parts[i+1]
+			}
+		}
+	}
+	if i := strings.LastIndexByte(cmmt, '['); i != -1 {
+		if j := strings.LastIndexByte(cmmt, ']'); j > i {
+			h.Organism = strings.TrimSpace(cmmt[i+1 : j])
+		}
+	}
+	return h
+}
+
+// HeaderTemplate is a user's regular expression for headers. The named
+// groups accession, entry, organism, taxid and gene fill the fields of
+// the Header.
+type HeaderTemplate struct {
+	re *regexp.Regexp
+}
+
+// NewHeaderTemplate compiles a template such as
+//
+//	^(?P<accession>\S+) .*organism=(?P<organism>[^;]+)
+//
+// It must have at least one of the named groups and no others.
+func NewHeaderTemplate(expr string) (*HeaderTemplate, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	n := 0
+	for _, name := range re.SubexpNames()[1:] {
+		switch name {
+		case "accession", "entry", "organism", "taxid", "gene":
+			n++
+		case "":
+		default:
+			return nil, fmt.Errorf("header template: unknown group name \"%s\"", name)
+		}
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("header template \"%s\" has no named groups", expr)
+	}
+	return &HeaderTemplate{re: re}, nil
+}
+
+// parse fills a Header if the template matches.
+func (tmpl *HeaderTemplate) parse(cmmt string) (Header, bool) {
+	m := tmpl.re.FindStringSubmatch(cmmt)
+	if m == nil {
+		return Header{}, false
+	}
+	var h Header
+	for i, name := range tmpl.re.SubexpNames() {
+		val := strings.TrimSpace(m[i])
+		switch name {
+		case "accession":
+			h.Accession = val
+		case "entry":
+			h.EntryName = val
+		case "organism":
+			h.Organism = val
+		case "taxid":
+			h.TaxID = val
+		case "gene":
+			h.Gene = val
+		}
+	}
+	return h, true
+}
+
+// ParseHeader picks the fields out of a comment line. The templates
+// are tried first, in order, then UniProt, then NCBI style.
+func ParseHeader(cmmt string, templates ...*HeaderTemplate) Header {
+	cmmt = strings.TrimLeft(cmmt, "> \t")
+	for _, tmpl := range templates {
+		if h, ok := tmpl.parse(cmmt); ok {
+			return h
+		}
+	}
+	if h, ok := uniprotHeader(cmmt); ok {
+		return h
+	}
+	return ncbiHeader(cmmt)
+}
+
+// parseHeaders picks apart every comment once, after reading, so the
+// accessors below do not have to.
+func (seqgrp *SeqGrp) parseHeaders(templates []*HeaderTemplate) {
+	for i := range seqgrp.seqs {
+		ss := &seqgrp.seqs[i]
+		ss.hdr = ParseHeader(ss.cmmt, templates...)
+	}
+}
+
+// Header returns the fields from the sequence's comment, as they were
+// found when it was read, using the Templates in the Options.
+func (s seq) Header() Header { return s.hdr }
+
+// Accession returns the accession from the comment, like P69905.
+func (s seq) Accession() string { return s.hdr.Accession }
+
+// EntryName returns the UniProt entry name, like HBA_HUMAN.
+func (s seq) EntryName() string { return s.hdr.EntryName }
+
+// Organism returns the organism from the comment.
+func (s seq) Organism() string { return s.hdr.Organism }
+
+// TaxID returns the taxon identifier from the comment.
+func (s seq) TaxID() string { return s.hdr.TaxID }
+
+// Gene returns the gene name from the comment.
+func (s seq) Gene() string { return s.hdr.Gene }
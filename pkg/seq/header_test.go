@@ -0,0 +1,111 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestParseHeader tries UniProt, NCBI and something we do not know
+func TestParseHeader(t *testing.T) {
+	data := []struct {
+		cmmt string
+		want Header
+	}{
+		{" sp|P69905|HBA_HUMAN Hemoglobin subunit alpha OS=Homo sapiens OX=9606 GN=HBA1 PE=1 SV=2",
+			Header{"P69905", "HBA_HUMAN", "Homo sapiens", "9606", "HBA1"}},
+		{"tr|A0A024R161|A0A024R161_HUMAN Guanine OS=Homo sapiens (Human) OX=9606",
+			Header{Accession: "A0A024R161", EntryName: "A0A024R161_HUMAN",
+				Organism: "Homo sapiens (Human)", TaxID: "9606"}},
+		{"NP_000549.1 hemoglobin subunit alpha [Homo sapiens]",
+			Header{Accession: "NP_000549.1", Organism: "Homo sapiens"}},
+		{"gi|4504347|ref|NP_000549.1| hemoglobin [Homo sapiens]",
+			Header{Accession: "NP_000549.1", Organism: "Homo sapiens"}},
+		{"  xyz.123 comment here [  mus musculus ]",
+			Header{Accession: "xyz.123", Organism: "mus musculus"}},
+		{"", Header{}},
+	}
+	for _, d := range data {
+		if got := ParseHeader(d.cmmt); got != d.want {
+			t.Fatalf("%s\nwanted %+v\ngot    %+v", d.cmmt, d.want, got)
+		}
+	}
+}
+
+// TestHeaderTemplate uses a template for a header we would not
+// otherwise understand.
+func TestHeaderTemplate(t *testing.T) {
+	tmpl, err := NewHeaderTemplate(`^(?P<accession>\S+) .*organism=(?P<organism>[^;]+);taxid=(?P<taxid>\d+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmmt := "ABC123 something organism=Bos taurus;taxid=9913"
+	want := Header{Accession: "ABC123", Organism: "Bos taurus", TaxID: "9913"}
+	if got := ParseHeader(cmmt, tmpl); got != want {
+		t.Fatalf("wanted %+v got %+v", want, got)
+	}
+	if got := ParseHeader("sp|P1|X_Y OS=Z", tmpl); got.Accession != "P1" {
+		t.Fatal("template did not match, should fall back to uniprot, got", got)
+	}
+	for _, bad := range []string{`(\S+)`, `(?P<species>\S+)`, `(`} {
+		if _, err := NewHeaderTemplate(bad); err == nil {
+			t.Fatal("should fail on template", bad)
+		}
+	}
+}
+
+// TestFindNdxHeader finds sequences by accession and entry name, even
+// if an earlier comment contains the same string.
+func TestFindNdxHeader(t *testing.T) {
+	set := `> sp|P69905|HBA_HUMAN mentions P12345 OS=Homo sapiens
+ACD
+> sp|P12345|AAA_HUMAN second OS=Homo sapiens
+ACD
+`
+	var seqgrp SeqGrp
+	if err := ReadFasta(strings.NewReader(set), &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for s, want := range map[string]int{"P12345": 1, "HBA_HUMAN": 0, "mentions": 0} {
+		if n := seqgrp.FindNdx(s); n != want {
+			t.Fatal("looking for", s, "wanted", want, "got", n)
+		}
+	}
+	if sp, ok := seqgrp.SeqSlc()[1].Species(); !ok || sp != "Homo sapiens" {
+		t.Fatal("species got", sp)
+	}
+}
+
+// TestHeaderOnRead checks the comments are picked apart when they are
+// read, with the templates from the options, and again if they change.
+func TestHeaderOnRead(t *testing.T) {
+	tmpl, err := NewHeaderTemplate(`^(?P<accession>\S+) gene=(?P<gene>\S+)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := "> abc gene=xyz\nACD\n> sp|P1|X_Y OS=Z\nACD\n"
+	for _, s_opts := range []*Options{{}, {Templates: []*HeaderTemplate{tmpl}}} {
+		var seqgrp SeqGrp
+		if err := ReadFasta(strings.NewReader(set), &seqgrp, s_opts); err != nil {
+			t.Fatal(err)
+		}
+		seqs := seqgrp.SeqSlc()
+		if seqs[1].Accession() != "P1" || seqs[1].EntryName() != "X_Y" || seqs[1].Organism() != "Z" {
+			t.Fatalf("uniprot got %+v", seqs[1].Header())
+		}
+		want := ""
+		if s_opts.Templates != nil {
+			want = "xyz"
+		}
+		if g := seqs[0].Gene(); g != want {
+			t.Fatal("gene wanted", want, "got", g)
+		}
+		seqs[0].SetCmmt("sp|P2|W_V OX=7")
+		if seqs[0].Accession() != "P2" || seqs[0].TaxID() != "7" {
+			t.Fatalf("after SetCmmt got %+v", seqs[0].Header())
+		}
+	}
+}
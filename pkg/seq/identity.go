@@ -0,0 +1,159 @@
+// 17 Oct 2026
+// Identity weights, as used by plmc and EVcouplings. Each sequence gets
+// 1/n, where n is the number of sequences (itself included) at least
+// some fraction identical to it. The sum of the weights is the
+// effective number of sequences, Neff.
+// This needs every pair of sequences, so it is the slow part. Sequences
+// are packed eight symbols to a word and compared a word at a time. A
+// pair is given up as soon as it has too many differences to reach the
+// threshold, even if everything after matches. Pairs are spread over
+// SetNWorker goroutines, each with its own neighbour counts, which are
+// added up at the end.
+
+package seq
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"sync"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// IdentGaps says how gaps are treated in sequence identity.
+type IdentGaps byte
+
+const (
+	GapsMatch   IdentGaps = iota // A gap is a symbol, so two gaps are identical (plmc)
+	GapsIgnored                  // Only sites where both have a residue are compared
+)
+
+// lowBytes has the lowest bit of each byte set.
+const lowBytes = 0x0101010101010101
+
+// nzBytes counts the bytes in x which are not zero.
+func nzBytes(x uint64) int {
+	x |= x >> 4
+	x |= x >> 2
+	x |= x >> 1
+	return bits.OnesCount64(x & lowBytes)
+}
+
+// packSeqs packs the remapped sequences into words, nw per sequence.
+// The padding at the end is zero. If masks is wanted, it gets 0xff for
+// each residue and 0 for each gap.
+func (seqgrp *SeqGrp) packSeqs(wantMask bool) (words, masks []uint64, nw int) {
+	remap := seqgrp.remapTable()
+	nw = (seqgrp.GetLen() + 7) / 8
+	words = make([]uint64, len(seqgrp.seqs)*nw)
+	if wantMask {
+		masks = make([]uint64, len(words))
+	}
+	var buf, mbuf [8]byte
+	for is, ss := range seqgrp.seqs {
+		for iw := 0; iw < nw; iw++ {
+			buf, mbuf = [8]byte{}, [8]byte{}
+			for k := 0; k < 8 && iw*8+k < len(ss.seq); k++ {
+				c := remap[ss.seq[iw*8+k]]
+				buf[k] = c
+				if c != GapChar {
+					mbuf[k] = 0xff
+				}
+			}
+			words[is*nw+iw] = binary.LittleEndian.Uint64(buf[:])
+			if wantMask {
+				masks[is*nw+iw] = binary.LittleEndian.Uint64(mbuf[:])
+			}
+		}
+	}
+	return words, masks, nw
+}
+
+// IdentityWeights gives each sequence the weight 1/n, where n is the
+// number of sequences whose identity to it is thresh (like 0.8) or
+// more, counting itself. With GapsMatch, identity is the fraction of
+// all sites with the same symbol. With GapsIgnored, it is the fraction
+// of sites where both have a residue, and two sequences with no such
+// site are not neighbours. Symbols are remapped by the alphabet. The
+// weights add up to Neff. Pass them to SetSeqWeights. The sequences
+// must all be the same length.
+func (seqgrp *SeqGrp) IdentityWeights(thresh float32, gaps IdentGaps) []float32 {
+	nseq := len(seqgrp.seqs)
+	if nseq == 0 {
+		return nil
+	}
+	words, masks, nw := seqgrp.packSeqs(gaps == GapsIgnored)
+	ncol := seqgrp.GetLen()
+	// thresh is a float32, so 0.8 is not quite 0.8. Exactly 80 %
+	// identity has to pass.
+	const eps = 1e-6
+	th := float64(thresh) - eps
+	maxDiff := ncol - int(math.Ceil(th*float64(ncol)))
+	similar := func(i, j int) bool {
+		a, b := words[i*nw:(i+1)*nw], words[j*nw:(j+1)*nw]
+		if gaps == GapsMatch {
+			ndiff := 0
+			for k := range a {
+				if ndiff += nzBytes(a[k] ^ b[k]); ndiff > maxDiff {
+					return false
+				}
+			}
+			return true
+		}
+		ma, mb := masks[i*nw:(i+1)*nw], masks[j*nw:(j+1)*nw]
+		nboth, ndiff := 0, 0
+		for k := range a {
+			m := ma[k] & mb[k]
+			nboth += nzBytes(m)
+			ndiff += nzBytes((a[k] ^ b[k]) & m)
+			if nMost := nboth + 8*(nw-k-1); float64(ndiff) > (1-th)*float64(nMost) {
+				return false // Too many differences, even if the rest match
+			}
+		}
+		return nboth > 0 && float64(nboth-ndiff) >= th*float64(nboth)
+	}
+	nworker := max(min(seqgrp.nWorker, nseq/minShard), 1)
+	nbrs := make([][]int32, nworker) // neighbours found by each worker
+	var wg sync.WaitGroup
+	for iw := 0; iw < nworker; iw++ {
+		nbrs[iw] = make([]int32, nseq)
+		wg.Add(1)
+		go func(i0 int, nbr []int32) {
+			defer wg.Done()
+			for i := i0; i < nseq; i += nworker { // rows are dealt out, so
+				for j := i + 1; j < nseq; j++ { //   long and short ones mix
+					if similar(i, j) {
+						nbr[i]++
+						nbr[j]++
+					}
+				}
+			}
+		}(iw, nbrs[iw])
+	}
+	wg.Wait()
+	wt := make([]float32, nseq)
+	for i := range wt {
+		n := int32(1)
+		for _, nbr := range nbrs {
+			n += nbr[i]
+		}
+		wt[i] = 1 / float32(n)
+	}
+	return wt
+}
+
+// Neff is the effective number of sequences, the sum of the weights
+// from SetSeqWeights, or the number of sequences if there are none.
+// Henikoff weights are scaled to add up to the number of sequences, so
+// this is only interesting with IdentityWeights.
+func (seqgrp *SeqGrp) Neff() float32 {
+	if seqgrp.seqWt == nil {
+		return float32(len(seqgrp.seqs))
+	}
+	var sum float64
+	for _, w := range seqgrp.seqWt {
+		sum += float64(w)
+	}
+	return float32(sum)
+}
@@ -0,0 +1,83 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestIdentityWeights has two sequences 90 % identical, one with
+// nothing in common and a fragment. With gaps as symbols, the fragment
+// is only 60 % identical to anything. Ignoring gaps, it is the same as
+// the first two.
+func TestIdentityWeights(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AAAAAAAAAA", "AAAAAAAAAB", "BBBBBBBBBB", "AAAAAA----"})
+	tests := []struct {
+		gaps IdentGaps
+		want []float32
+		neff float32
+	}{
+		{GapsMatch, []float32{1. / 2, 1. / 2, 1, 1}, 3},
+		{GapsIgnored, []float32{1. / 3, 1. / 3, 1, 1. / 3}, 2},
+	}
+	for _, tt := range tests {
+		w := seqgrp.IdentityWeights(0.8, tt.gaps)
+		if !sliceEql(w, tt.want) {
+			t.Fatal("gaps", tt.gaps, "wanted", tt.want, "got", w)
+		}
+		seqgrp.SetSeqWeights(w)
+		if n := seqgrp.Neff(); !roughEql(n, tt.neff) {
+			t.Fatal("gaps", tt.gaps, "wanted Neff", tt.neff, "got", n)
+		}
+	}
+	if n := newProfile(t, seqgrp, false).SiteNeff(); !roughEql(n[0], 2) || !roughEql(n[9], 5./3) {
+		t.Fatal("site Neff", n)
+	}
+	w := Str2SeqGrp([]string{"AAAAAAAAAA", "AAAAAAAABB"}).IdentityWeights(0.8, GapsMatch)
+	if w[0] != 0.5 {
+		t.Fatal("exactly 80 % identical should be neighbours")
+	}
+}
+
+// TestIdentityPar checks the packed comparisons against a plain loop,
+// with a length which is not a whole number of words, and checks that
+// more goroutines give the same weights.
+func TestIdentityPar(t *testing.T) {
+	const thresh = 0.15 // 15 % in whole numbers below
+	seqgrp := randAln(800, 43)
+	ss := seqgrp.SeqSlc()
+	for _, gaps := range []IdentGaps{GapsMatch, GapsIgnored} {
+		want := make([]float32, len(ss))
+		for i := range ss {
+			n := 0
+			for j := range ss {
+				a, b := ss[i].GetSeq(), ss[j].GetSeq()
+				nsame, nsite := 0, 0
+				for k := range a {
+					if gaps == GapsIgnored && (a[k] == '-' || b[k] == '-') {
+						continue
+					}
+					nsite++
+					if a[k] == b[k] {
+						nsame++
+					}
+				}
+				if nsite > 0 && 100*nsame >= 15*nsite {
+					n++
+				}
+			}
+			want[i] = 1 / float32(n)
+		}
+		for _, nworker := range []int{1, 3} {
+			seqgrp.SetNWorker(nworker)
+			got := seqgrp.IdentityWeights(thresh, gaps)
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("gaps %d, %d workers, seq %d got %g want %g", gaps, nworker, i, got[i], want[i])
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestMmap reads files with and without the Mmap option and checks we
+// get the same sequences. There are clean lines, wrapped lines, white
+// space, carriage returns and an empty sequence.
+func TestMmap(t *testing.T) {
+	data := []struct {
+		s      string
+		s_opts Options
+	}{
+		{"> s1\nACDEF\n> s2\nAC-EF\n> s3\nacdef", Options{}},
+		{"> s1\nAC\nDEF\n>s2 x\nA C-E F\r\n> s3\r\nacdef\n", Options{}},
+		{"> s1\nACDEFGH\n> s2\nAC\n> s3\n\n> s4\nA-C\n", Options{DiffLenSeq: true, ZeroLenOK: true}},
+		{"> s1\nAC-DEF\n> s2\nAC--EF\n", Options{RmvGapsRd: true, DiffLenSeq: true}},
+		{"> s1\nACDEFGH\n> s2\nAC-EFGH\n", Options{RangeStart: 2, RangeEnd: 4}},
+		{"# STOCKHOLM 1.0\ns1 ACDEF\ns2 AC-EF\n//\n", Options{}},
+	}
+	for i, d := range data {
+		tmpname, err := wrtTmp(d.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpname)
+		s_opts := d.s_opts
+		want, err := Readfile(tmpname, &s_opts)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		s_opts.Mmap = true
+		got, err := Readfile(tmpname, &s_opts)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if got.NSeq() != want.NSeq() {
+			t.Fatal(i, "wanted", want.NSeq(), "seqs, got", got.NSeq())
+		}
+		for j, ss := range got.SeqSlc() {
+			w := want.SeqSlc()[j]
+			if string(ss.GetSeq()) != string(w.GetSeq()) || ss.Cmmt() != w.Cmmt() {
+				t.Fatalf("test %d seq %d wanted %q %q got %q %q", i, j,
+					w.Cmmt(), w.GetSeq(), ss.Cmmt(), ss.GetSeq())
+			}
+		}
+		if err := got.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestMmapBroken checks errors come back as without mapping
+func TestMmapBroken(t *testing.T) {
+	for _, s := range []string{"> s1\nACDEF\n> s2\nACD\n", "> s1\nACDEF\n> s2\n\n", ""} {
+		tmpname, err := wrtTmp(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpname)
+		if _, err := Readfile(tmpname, &Options{Mmap: true}); err == nil {
+			t.Fatalf("should fail reading %q", s)
+		}
+	}
+}
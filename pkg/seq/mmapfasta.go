@@ -0,0 +1,139 @@
+// 17 Oct 2026
+// Reading fasta files by mapping them into memory. Sequences are not
+// copied out of the file. Each sequence is a slice pointing into the
+// mapping. The mapping is private (copy on write), so if a sequence
+// has white space or is spread over several lines, we remove the white
+// space in place and only the pages we write to get copied. A sequence
+// that sits on one line without any white space is used where it lies.
+// After parsing, the options (gap removal, ranges, length checks) are
+// applied by finishGrp, as for the other formats.
+
+package seq
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/andrew-torda/seq_compat/pkg/white"
+	"github.com/edsrzf/mmap-go"
+)
+
+// isSpace is true for the characters white.Remove takes out.
+var isSpace = [256]bool{
+	0: true, '\t': true, '\n': true, '\v': true, '\f': true, '\r': true, ' ': true,
+}
+
+// clean removes white space from a sequence in place. It does not
+// touch anything before the first white space, so a clean line is left
+// alone and its memory is not written to.
+func clean(s []byte) []byte {
+	s = bytes.TrimRight(s, "\r\n")
+	for i, c := range s {
+		if isSpace[c] {
+			t := s[i:]
+			white.Remove(&t)
+			return s[:i+len(t)]
+		}
+	}
+	return s
+}
+
+// sliceParse breaks a block of fasta formatted bytes into sequences.
+// The sequences point into b, which may be changed when white space is
+// removed. Comments are copied. Like the lexer in ReadFasta, a comment
+// runs to the end of its line and a sequence runs to the next ">",
+// even in the middle of a line. Line numbers count from the start of b.
+func sliceParse(b []byte) ([]seq, error) {
+	orig := b
+	b = bytes.TrimLeft(b, " \t\r\n")
+	if len(b) == 0 {
+		return nil, nil
+	}
+	nline := 1 + bytes.Count(orig[:len(orig)-len(b)], []byte{'\n'})
+	if b[0] != cmmt_char {
+		return nil, &ParseError{Record: 0, Line: nline, Err: errNoCmmt}
+	}
+	seqs := make([]seq, 0, bytes.Count(b, []byte{cmmt_char}))
+	for len(b) > 0 {
+		nl := bytes.IndexByte(b, '\n')
+		if nl == -1 {
+			nl = len(b) - 1
+		}
+		cmmt := string(bytes.TrimSuffix(b[1:nl+1], []byte{'\n'}))
+		b = b[nl+1:]
+		var s []byte // As in the lexer, any ">" starts the next record
+		if end := bytes.IndexByte(b, cmmt_char); end == -1 {
+			s, b = b, nil
+		} else {
+			s, b = b[:end], b[end:]
+		}
+		next := nline + 1 + bytes.Count(s, []byte{'\n'}) // clean changes s
+		seqs = append(seqs, seq{cmmt: cmmt, seq: clean(s), line: nline})
+		nline = next
+	}
+	return seqs, nil
+}
+
+// isFasta is true if the first thing in head is a ">".
+func isFasta(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	return len(head) > 0 && head[0] == cmmt_char
+}
+
+// readMmap maps a file and reads it with sliceParse, in parallel if
+// s_opts.NWorker is more than one. With the Mmap option, the mapping is
+// kept in seqgrp until Close is called. Otherwise, the sequences are
+// copied out and the mapping is let go. Anything other than plain fasta
+// (compressed files, other formats, A2M) is read from the mapping by the
+// usual readers and the mapping is let go afterwards.
+func readMmap(fname string, seqgrp *SeqGrp, s_opts *Options) error {
+	const sniffLen = 512
+	fp, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer fp.Close() // The mapping lives on after the file is closed
+	if fi, err := fp.Stat(); err != nil {
+		return err
+	} else if fi.Size() == 0 {
+		return ErrNoSequences
+	}
+	mm, err := mmap.Map(fp, mmap.COPY, 0)
+	if err != nil {
+		return err
+	}
+	head := mm[:min(len(mm), sniffLen)]
+	format, err := inFormat(head, s_opts)
+	if err != nil {
+		mm.Unmap()
+		return err
+	}
+	if format.Name != fastaName || !isFasta(head) {
+		defer mm.Unmap()
+		return readAny(bytes.NewReader(mm), seqgrp, s_opts)
+	}
+	bounds, err := readChunks(mm, seqgrp, s_opts)
+	if err != nil {
+		mm.Unmap()
+		return err
+	}
+	if !s_opts.Mmap {
+		copyOut(seqgrp.seqs, bounds)
+		return mm.Unmap()
+	}
+	seqgrp.mapped = mm
+	return nil
+}
+
+// Close lets go of the memory map if the sequences were read with the
+// Mmap option. After this, the sequences must not be used. Without the
+// Mmap option, it does nothing.
+func (seqgrp *SeqGrp) Close() error {
+	if seqgrp.mapped == nil {
+		return nil
+	}
+	seqgrp.seqs = nil
+	err := seqgrp.mapped.Unmap()
+	seqgrp.mapped = nil
+	return err
+}
@@ -0,0 +1,171 @@
+// 17 Oct 2026
+// GCG MSF format. A file looks like
+//
+//	!!AA_MULTIPLE_ALIGNMENT 1.0
+//
+//	 aln.msf  MSF: 10  Type: P  Check:  7093  ..
+//
+//	 Name: seq1  Len:  10  Check:  3511  Weight:  1.00
+//	 Name: seq2  Len:  10  Check:  3582  Weight:  1.00
+//
+//	//
+//
+//	seq1  ACDEF..HIK
+//	seq2  ACDEFGLHIK
+//
+// Everything before the "//" is header. We take the sequence order from
+// the "Name:" lines. After the "//", come interleaved blocks, possibly with
+// lines of column numbers. Gaps are "." or "~".
+
+package seq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+const (
+	msfMagic   = "MSF:"
+	msfGaps    = ".~"
+	msfGap     = '.' // What we write for a gap
+	msfPerLine = 50  // residues per line
+	msfPerWord = 10  // residues between spaces
+)
+
+// gcgCheck returns the GCG checksum of a sequence, as it will be
+// written, so gaps should already be "." characters.
+func gcgCheck(s []byte) int {
+	check := 0
+	for i, c := range s {
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		check += ((i % 57) + 1) * int(c)
+	}
+	return check % 10000
+}
+
+// allDigits is true for a line of column numbers.
+func allDigits(f []string) bool {
+	for _, s := range f {
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ReadMSF reads a GCG MSF format alignment into seqgrp. The options
+// work as for ReadFasta.
+func ReadMSF(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const noSep = "msf file has no \"//\" separating header and alignment"
+	const unknown = "msf: sequence \"%s\" not in header"
+	var blocks blockReader
+	inHeader := true
+	scanner := newScanner(rdr)
+	for nline := 1; scanner.Scan(); nline++ {
+		line := strings.TrimSpace(scanner.Text())
+		f := strings.Fields(line)
+		if inHeader {
+			switch {
+			case strings.HasPrefix(line, "//"):
+				inHeader = false
+			case len(f) >= 2 && f[0] == "Name:":
+				blocks.add(f[1], nil)
+			}
+			continue
+		}
+		if len(f) < 2 || allDigits(f) {
+			continue
+		}
+		if _, ok := blocks.byName[f[0]]; !ok {
+			return &ParseError{Record: -1, Line: nline, Err: fmt.Errorf(unknown, f[0])}
+		}
+		for _, piece := range f[1:] {
+			blocks.add(f[0], []byte(piece))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if inHeader {
+		return errors.New(noSep)
+	}
+	blocks.toGrp(seqgrp, msfGaps)
+	return finishGrp(seqgrp, s_opts)
+}
+
+// WriteMSF writes an alignment in GCG MSF format. Gaps are written as
+// ".". Names are the first word of each comment. With RmvGapsWrt, the
+// sequences are written without gaps, each with its own length in the
+// "Len:" field, and MSF: is the longest.
+func WriteMSF(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error {
+	names, seqs, err := alnSeqs(seqgrp, s_opts.RmvGapsWrt)
+	if err != nil {
+		return err
+	}
+	out := make([][]byte, len(seqs)) // sequences with GCG gaps
+	checks := make([]int, len(seqs))
+	total, alnLen := 0, 0
+	for i, ss := range seqs {
+		out[i] = bytes.ReplaceAll(ss.seq, []byte{common.GapChar}, []byte{msfGap})
+		checks[i] = gcgCheck(out[i])
+		total += checks[i]
+		alnLen = max(alnLen, len(out[i]))
+	}
+	total %= 10000
+	alnType, magic := "N", "!!NA_MULTIPLE_ALIGNMENT 1.0"
+	if seqgrp.GetType() == Protein {
+		alnType, magic = "P", "!!AA_MULTIPLE_ALIGNMENT 1.0"
+	}
+	width := nameWidth(names)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n\n", magic)
+	fmt.Fprintf(bw, " MSF: %d  Type: %s  Check: %5d  ..\n\n", alnLen, alnType, total)
+	for i, name := range names {
+		fmt.Fprintf(bw, " Name: %-*s  Len: %5d  Check: %5d  Weight:  1.00\n",
+			width, name, len(out[i]), checks[i])
+	}
+	fmt.Fprint(bw, "\n//\n")
+	for start := 0; start < alnLen; start += msfPerLine {
+		end := start + msfPerLine
+		if end > alnLen {
+			end = alnLen
+		}
+		bw.WriteByte('\n')
+		for i, s := range out {
+			if start >= len(s) {
+				continue
+			}
+			fmt.Fprintf(bw, "%-*s ", width, names[i])
+			for j := start; j < min(end, len(s)); j += msfPerWord {
+				k := min(j+msfPerWord, end, len(s))
+				fmt.Fprintf(bw, " %s", s[j:k])
+			}
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// isMSF looks at the start of a file and says if it is MSF format.
+func isMSF(head []byte) bool {
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if bytes.HasPrefix(head, []byte("!!AA_MULTIPLE_ALIGNMENT")) ||
+		bytes.HasPrefix(head, []byte("!!NA_MULTIPLE_ALIGNMENT")) {
+		return true
+	}
+	if len(head) == 0 || head[0] == cmmtChar {
+		return false
+	}
+	return bytes.Contains(head, []byte(msfMagic))
+}
@@ -0,0 +1,325 @@
+// 17 Oct 2026
+// NEXUS format, as used by MrBayes and PAUP. We only care about the
+// DATA (or CHARACTERS) block, which looks like
+//
+//	#NEXUS
+//	BEGIN DATA;
+//	  DIMENSIONS NTAX=2 NCHAR=12;
+//	  FORMAT DATATYPE=PROTEIN MISSING=? GAP=- INTERLEAVE;
+//	  MATRIX
+//	    seq1       ACDEFGHIKL
+//	    'seq two'  ACDEFGHIKL
+//
+//	    seq1       MN
+//	    'seq two'  MN
+//	  ;
+//	END;
+//
+// Commands and keywords do not care about case. Anything in square
+// brackets is a comment. Names with spaces or punctuation are quoted.
+// Other blocks (TAXA, TREES, ...) are skipped. If the format says
+// MATCHCHAR=., a "." means "the same as the first sequence".
+
+package seq
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+const (
+	nexusMagic   = "#NEXUS"
+	nexusPerLine = 60 // residues per line in interleaved output
+	nexusPunct   = "()[]{}/\\,;:=*'\"`<>^ \t"
+)
+
+// nexusUncomment removes [comments], which may be nested, but leaves
+// anything inside quotes alone.
+func nexusUncomment(s string) string {
+	var b strings.Builder
+	depth := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted:
+			if c == '\'' {
+				quoted = false
+			}
+		case c == '[':
+			depth++
+			continue
+		case c == ']' && depth > 0:
+			depth--
+			continue
+		case depth > 0:
+			continue
+		case c == '\'':
+			quoted = true
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// nexusCommands splits text at the ";" which end commands, but not at
+// one inside a quoted name like 'a;b'.
+func nexusCommands(text string) []string {
+	var cmds []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\'':
+			quoted = !quoted // two quotes in a name toggle twice
+		case ';':
+			if !quoted {
+				cmds = append(cmds, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(cmds, text[start:])
+}
+
+// nexusTokens splits a line into words, keeping quoted names together
+// (without the quotes). Two quotes in a row inside a name are one quote.
+func nexusTokens(line string) []string {
+	var tokens []string
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '\'':
+			var b strings.Builder
+			for i++; i < len(line); i++ {
+				if line[i] == '\'' {
+					if i+1 < len(line) && line[i+1] == '\'' {
+						b.WriteByte('\'')
+						i++
+						continue
+					}
+					i++
+					break
+				}
+				b.WriteByte(line[i])
+			}
+			tokens = append(tokens, b.String())
+		default:
+			j := i
+			for j < len(line) && line[j] != ' ' && line[j] != '\t' && line[j] != '\r' {
+				j++
+			}
+			tokens = append(tokens, line[i:j])
+			i = j
+		}
+	}
+	return tokens
+}
+
+// nexusFormat holds what we need from the DIMENSIONS and FORMAT commands.
+type nexusFormat struct {
+	nchar      int
+	gap        byte
+	matchchar  byte
+	interleave bool
+}
+
+// nexusSettings picks out KEY=value pairs (and lone keywords) from a
+// command like "FORMAT DATATYPE=DNA GAP=- INTERLEAVE".
+func nexusSettings(cmd string, format *nexusFormat) error {
+	cmd = strings.ReplaceAll(cmd, " = ", "=")
+	for _, word := range strings.Fields(cmd)[1:] {
+		key, val, _ := strings.Cut(word, "=")
+		switch strings.ToUpper(key) {
+		case "NCHAR":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return fmt.Errorf("nexus: bad NCHAR \"%s\"", val)
+			}
+			format.nchar = n
+		case "GAP":
+			if len(val) == 1 {
+				format.gap = val[0]
+			}
+		case "MATCHCHAR":
+			if len(val) == 1 {
+				format.matchchar = val[0]
+			}
+		case "INTERLEAVE":
+			format.interleave = val == "" || strings.EqualFold(val, "yes")
+		}
+	}
+	return nil
+}
+
+// nexusMatrix reads the rows of a MATRIX command. If the data is not
+// interleaved, a sequence may be spread over several lines, so we keep
+// adding to a sequence until it has nchar characters.
+func nexusMatrix(matrix string, format *nexusFormat) (*blockReader, error) {
+	const tooLong = "nexus: sequence %s is longer than NCHAR=%d"
+	var blocks blockReader
+	var cur string // sequence being built, when not interleaved
+	for _, line := range strings.Split(matrix, "\n") {
+		tokens := nexusTokens(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		if format.interleave || format.nchar == 0 || cur == "" {
+			cur = tokens[0]
+			tokens = tokens[1:]
+			blocks.add(cur, nil)
+		}
+		blocks.add(cur, []byte(strings.Join(tokens, "")))
+		if !format.interleave && format.nchar > 0 {
+			if l := len(blocks.byName[cur]); l > format.nchar {
+				return nil, fmt.Errorf(tooLong, cur, format.nchar)
+			} else if l == format.nchar {
+				cur = ""
+			}
+		}
+	}
+	return &blocks, nil
+}
+
+// ReadNexus reads the DATA or CHARACTERS block from a NEXUS file into
+// seqgrp. The options work as for ReadFasta.
+func ReadNexus(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const noBlock = "nexus: no DATA or CHARACTERS block with a MATRIX"
+	b, err := io.ReadAll(rdr)
+	if err != nil {
+		return err
+	}
+	text := strings.TrimSpace(nexusUncomment(string(b)))
+	if isNexus([]byte(text)) {
+		text = text[len(nexusMagic):]
+	}
+	format := nexusFormat{gap: common.GapChar}
+	inBlock := false
+	var blocks *blockReader
+	for _, cmd := range nexusCommands(text) {
+		cmd = strings.TrimSpace(cmd)
+		words := strings.Fields(cmd)
+		if len(words) == 0 {
+			continue
+		}
+		keyword := strings.ToUpper(words[0])
+		switch {
+		case keyword == "BEGIN" && len(words) > 1:
+			blk := strings.ToUpper(words[1])
+			inBlock = blk == "DATA" || blk == "CHARACTERS"
+		case keyword == "END" || keyword == "ENDBLOCK":
+			inBlock = false
+		case !inBlock:
+			continue
+		case keyword == "DIMENSIONS" || keyword == "FORMAT":
+			if err := nexusSettings(cmd, &format); err != nil {
+				return err
+			}
+		case keyword == "MATRIX":
+			if blocks, err = nexusMatrix(cmd[len("MATRIX"):], &format); err != nil {
+				return err
+			}
+		}
+	}
+	if blocks == nil {
+		return errors.New(noBlock)
+	}
+	gaps := ""
+	if format.gap != common.GapChar {
+		gaps = string(format.gap)
+	}
+	blocks.toGrp(seqgrp, gaps)
+	if mc := format.matchchar; mc != 0 && len(seqgrp.seqs) > 1 {
+		first := seqgrp.seqs[0].seq
+		for _, ss := range seqgrp.seqs[1:] {
+			for i, c := range ss.seq {
+				if c == mc && i < len(first) {
+					ss.seq[i] = first[i]
+				}
+			}
+		}
+	}
+	return finishGrp(seqgrp, s_opts)
+}
+
+// nexusName quotes a name if it has punctuation or spaces in it.
+func nexusName(name string) string {
+	if !strings.ContainsAny(name, nexusPunct) {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// WriteNexus writes an alignment as a NEXUS file with a DATA block. If
+// s_opts.Interleave is set, the matrix is written in blocks. Names are
+// the first word of each comment and must not be repeated. Rows have to
+// be aligned, so RmvGapsWrt is an error.
+func WriteNexus(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error {
+	if s_opts.RmvGapsWrt {
+		return fmt.Errorf(alignedOnly, "nexus")
+	}
+	names, seqs, err := alnSeqs(seqgrp, false)
+	if err != nil {
+		return err
+	}
+	if err := uniqueNames(names, "nexus"); err != nil {
+		return err
+	}
+	for i := range names {
+		names[i] = nexusName(names[i])
+	}
+	datatype := "PROTEIN"
+	switch seqgrp.GetType() {
+	case DNA, Ntide:
+		datatype = "DNA"
+	case RNA:
+		datatype = "RNA"
+	}
+	interleave := ""
+	if s_opts.Interleave {
+		interleave = " INTERLEAVE"
+	}
+	width := nameWidth(names)
+	alnLen := seqs[0].Len()
+	perLine := alnLen
+	if s_opts.Interleave {
+		perLine = nexusPerLine
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n\nBEGIN DATA;\n", nexusMagic)
+	fmt.Fprintf(bw, "  DIMENSIONS NTAX=%d NCHAR=%d;\n", len(seqs), alnLen)
+	fmt.Fprintf(bw, "  FORMAT DATATYPE=%s MISSING=? GAP=%c%s;\n", datatype, common.GapChar, interleave)
+	fmt.Fprint(bw, "  MATRIX\n")
+	for start := 0; start < alnLen || start == 0; start += perLine {
+		end := start + perLine
+		if end > alnLen {
+			end = alnLen
+		}
+		if start > 0 {
+			bw.WriteByte('\n')
+		}
+		for i, ss := range seqs {
+			fmt.Fprintf(bw, "    %-*s  %s\n", width, names[i], ss.seq[start:end])
+		}
+		if perLine == 0 {
+			break
+		}
+	}
+	fmt.Fprint(bw, "  ;\nEND;\n")
+	return bw.Flush()
+}
+
+// isNexus looks at the start of a file and says if it is NEXUS format.
+func isNexus(head []byte) bool {
+	s := strings.TrimSpace(string(head))
+	return len(s) >= len(nexusMagic) && strings.EqualFold(s[:len(nexusMagic)], nexusMagic)
+}
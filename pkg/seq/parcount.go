@@ -0,0 +1,63 @@
+// 17 Oct 2026
+// Parallel counting. For deep alignments, UsageSite spends its time in
+// one double loop over sequences and sites. countShards cuts the
+// sequences into one contiguous piece per worker. Each worker counts
+// into its own integer matrix and the matrices are added up at the end.
+// Integer sums do not care about order, so the result is exactly what
+// one goroutine would get.
+
+package seq
+
+import (
+	"sync"
+
+	"github.com/andrew-torda/matrix"
+)
+
+// minShard is the fewest sequences worth giving to a goroutine.
+const minShard = 256
+
+// SetNWorker says how many goroutines UsageSite and NewProfile may use
+// for counting. One or less means count in the calling goroutine. The
+// results are the same either way. Counting with quality weights from
+// SetQualWeight is always done in one goroutine.
+func (seqgrp *SeqGrp) SetNWorker(n int) { seqgrp.nWorker = n }
+
+// countShards is countSeqs, spread over seqgrp.nWorker goroutines.
+func (seqgrp *SeqGrp) countShards(raw [][]int32, term []int32) {
+	nseq := len(seqgrp.seqs)
+	nworker := min(seqgrp.nWorker, nseq/minShard)
+	if nworker <= 1 {
+		seqgrp.countSeqs(seqgrp.seqs, raw, term)
+		return
+	}
+	nrow, ncol := len(raw), len(raw[0])
+	raws := make([][][]int32, nworker)
+	terms := make([][]int32, nworker)
+	raws[0], terms[0] = raw, term // The first shard goes straight in
+	var wg sync.WaitGroup
+	for iw := 0; iw < nworker; iw++ {
+		if iw > 0 {
+			raws[iw] = matrix.NewIMatrix2d(nrow, ncol).Mat
+			if term != nil {
+				terms[iw] = make([]int32, ncol)
+			}
+		}
+		wg.Add(1)
+		go func(iw int, shard []seq) {
+			defer wg.Done()
+			seqgrp.countSeqs(shard, raws[iw], terms[iw])
+		}(iw, seqgrp.seqs[iw*nseq/nworker:(iw+1)*nseq/nworker])
+	}
+	wg.Wait()
+	for iw := 1; iw < nworker; iw++ {
+		for irow := range raw {
+			for icol, n := range raws[iw][irow] {
+				raw[irow][icol] += n
+			}
+		}
+		for icol, n := range terms[iw] {
+			term[icol] += n
+		}
+	}
+}
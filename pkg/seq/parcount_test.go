@@ -0,0 +1,49 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// sameProfile says if two profiles have exactly the same numbers.
+func sameProfile(p, q *Profile) bool {
+	for _, c := range p.Symbols() {
+		for i := 0; i < p.Len(); i++ {
+			if p.Count(c, i) != q.Count(c, i) || p.Weight(c, i) != q.Weight(c, i) || p.Freq(c, i) != q.Freq(c, i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// TestParallelCount counts with different numbers of workers, with and
+// without terminal gap weights and from the column-major copy. Every
+// result must be bit for bit the same as counting in one goroutine.
+func TestParallelCount(t *testing.T) {
+	seqgrp := randAln(2000, 50)
+	for _, termWt := range []float32{1, 0.3} {
+		seqgrp.SetTermGapWeight(termWt)
+		seqgrp.SetNWorker(1)
+		serial := newProfile(t, seqgrp, false)
+		for _, nworker := range []int{2, 3, 8, 100} {
+			seqgrp.SetNWorker(nworker)
+			if !sameProfile(serial, newProfile(t, seqgrp, false)) {
+				t.Fatalf("term weight %g, %d workers differ from serial", termWt, nworker)
+			}
+		}
+	}
+	seqgrp.SetTermGapWeight(0.3)
+	seqgrp.SetNWorker(1)
+	serial := newProfile(t, seqgrp, true)
+	if _, err := seqgrp.Transpose(); err != nil {
+		t.Fatal(err)
+	}
+	seqgrp.SetNWorker(4)
+	if !sameProfile(serial, newProfile(t, seqgrp, true)) {
+		t.Fatal("parallel counting from columns differs from serial")
+	}
+}
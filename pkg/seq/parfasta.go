@@ -0,0 +1,139 @@
+// 17 Oct 2026
+// Parallel fasta parsing. The whole input is in memory (mapped or read
+// in one go). We cut it into pieces at lines starting with ">", the
+// same places numseq counts, and give each piece to sliceParse in its
+// own goroutine. The pieces are put back together in their original
+// order and finishGrp applies the options, so the result is the same as
+// ReadFasta would give.
+
+package seq
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+	"github.com/andrew-torda/seq_compat/pkg/white"
+)
+
+// splitChunks cuts b into about n pieces. Every piece, except perhaps
+// the first, starts with ">" and no sequence is split.
+func splitChunks(b []byte, n int) [][]byte {
+	var nlcmmt = []byte{'\n', cmmt_char}
+	if n < 1 {
+		n = 1
+	}
+	size := len(b)/n + 1
+	var chunks [][]byte
+	for len(b) > 0 {
+		if len(b) <= size {
+			chunks = append(chunks, b)
+			break
+		}
+		i := bytes.Index(b[size:], nlcmmt)
+		if i == -1 {
+			chunks = append(chunks, b)
+			break
+		}
+		cut := size + i + 1
+		chunks = append(chunks, b[:cut])
+		b = b[cut:]
+	}
+	return chunks
+}
+
+// parseChunks runs sliceParse on each piece of b in parallel. Extra
+// gap characters are converted and, if RmvGapsRd is set, gaps are
+// removed there too, since it is per sequence work. It returns all the
+// sequences in order and the index where each piece starts. Each piece
+// counts its lines from one, so the lines before it are added on
+// afterwards.
+func parseChunks(b []byte, nworker int, s_opts *Options) ([]seq, []int, error) {
+	chunks := splitChunks(b, nworker)
+	parsed := make([][]seq, len(chunks))
+	errs := make([]error, len(chunks))
+	nlines := make([]int, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(i int, chunk []byte) {
+			defer wg.Done()
+			nlines[i] = bytes.Count(chunk, []byte{'\n'})
+			parsed[i], errs[i] = sliceParse(chunk)
+			for j := range parsed[i] {
+				if s_opts.GapChars != "" {
+					toGapChar(parsed[i][j].seq, s_opts.GapChars)
+				}
+				if s_opts.RmvGapsRd {
+					white.CharRemove(&parsed[i][j].seq, common.GapChar)
+				}
+			}
+		}(i, chunk)
+	}
+	wg.Wait()
+	n, offset := 0, 0
+	for i := range parsed {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		for j := range parsed[i] {
+			parsed[i][j].line += offset
+		}
+		offset += nlines[i]
+		n += len(parsed[i])
+	}
+	seqs := make([]seq, 0, n)
+	bounds := make([]int, 0, len(parsed)+1)
+	for _, p := range parsed {
+		bounds = append(bounds, len(seqs))
+		seqs = append(seqs, p...)
+	}
+	bounds = append(bounds, len(seqs))
+	return seqs, bounds, nil
+}
+
+// readChunks parses fasta in b, using s_opts.NWorker goroutines, and
+// applies the options. The sequences point into b. It returns where
+// each piece starts in seqgrp.seqs, for copyOut.
+func readChunks(b []byte, seqgrp *SeqGrp, s_opts *Options) ([]int, error) {
+	if err := checkBroken(s_opts); err != nil {
+		return nil, err
+	}
+	seqs, bounds, err := parseChunks(b, s_opts.NWorker, s_opts)
+	if err != nil {
+		return nil, err
+	}
+	seqgrp.seqs = seqs
+	noGaps := *s_opts // Gaps have already gone
+	noGaps.RmvGapsRd = false
+	noGaps.GapChars = ""
+	if err := finishGrp(seqgrp, &noGaps); err != nil {
+		seqgrp.seqs = nil
+		return nil, err
+	}
+	return bounds, nil
+}
+
+// copyOut moves sequences out of a mapping, so the mapping can be let
+// go. Each piece from parseChunks gets one block of memory and is
+// copied in its own goroutine.
+func copyOut(seqs []seq, bounds []int) {
+	var wg sync.WaitGroup
+	for i := 0; i+1 < len(bounds); i++ {
+		wg.Add(1)
+		go func(part []seq) {
+			defer wg.Done()
+			n := 0
+			for _, s := range part {
+				n += len(s.seq)
+			}
+			block := make([]byte, 0, n)
+			for j := range part {
+				start := len(block)
+				block = append(block, part[j].seq...)
+				part[j].seq = block[start:len(block):len(block)]
+			}
+		}(seqs[bounds[i]:bounds[i+1]])
+	}
+	wg.Wait()
+}
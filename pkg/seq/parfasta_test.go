@@ -0,0 +1,165 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/randseq"
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestSplitChunks checks every piece after the first starts a sequence
+// and nothing is lost.
+func TestSplitChunks(t *testing.T) {
+	s := ">a\nAC>DE\n>b\nACDE\n>c\nA\n>d\nACDEFGHIKLMN\n"
+	for n := 1; n < 8; n++ {
+		chunks := SplitChunks([]byte(s), n)
+		if string(bytes.Join(chunks, nil)) != s {
+			t.Fatal("lost something with", n, "chunks")
+		}
+		for _, c := range chunks[1:] {
+			if c[0] != '>' || c[len(c)-1] != '\n' {
+				t.Fatalf("n %d broken chunk %q", n, c)
+			}
+		}
+	}
+}
+
+// TestParallel reads random sequences with white space and gaps, with
+// one worker and with several, and checks the results are the same.
+func TestParallel(t *testing.T) {
+	var sb strings.Builder
+	args := randseq.RandSeqArgs{Wrtr: &sb, Cmmt: "rand", Nseq: 2000, Len: 150}
+	if err := randseq.RandSeqMain(&args); err != nil {
+		t.Fatal(err)
+	}
+	tmpname, err := wrtTmp(sb.String() + "> empty\n\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpname)
+	allOpts := []Options{
+		{DiffLenSeq: true, ZeroLenOK: true},
+		{DiffLenSeq: true, ZeroLenOK: true, RmvGapsRd: true},
+	}
+	for _, s_opts := range allOpts {
+		want, err := Readfile(tmpname, &s_opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mmap := range []bool{false, true} {
+			s_opts.NWorker, s_opts.Mmap = 4, mmap
+			got, err := Readfile(tmpname, &s_opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.NSeq() != want.NSeq() {
+				t.Fatal("wanted", want.NSeq(), "seqs, got", got.NSeq())
+			}
+			for i, ss := range got.SeqSlc() {
+				w := want.SeqSlc()[i]
+				if string(ss.GetSeq()) != string(w.GetSeq()) || ss.Cmmt() != w.Cmmt() {
+					t.Fatal("seq", i, "wanted", w.Cmmt(), "got", ss.Cmmt())
+				}
+			}
+			got.Close()
+			s_opts.NWorker, s_opts.Mmap = 0, false
+		}
+	}
+	if _, err := Readfile(tmpname, &Options{NWorker: 4}); err == nil {
+		t.Fatal("should fail with empty sequence")
+	}
+}
+
+// TestParallelOdd has fasta which is legal, but odd. A ">" in the
+// middle of a line starts a new record, there are blank lines before
+// the first one and white space before a ">". Reading in one goroutine,
+// in several and from a mapping must give the same sequences. Input
+// which does not start with ">" fails every way.
+func TestParallelOdd(t *testing.T) {
+	data := []string{
+		">a\nAC>DE\nFG\n>b\nACDE\n",
+		">a\nAC>DE\n>b\nACDE\n",
+		"\n\n>a\nACDE\n>b\nACDE\n",
+		">a\nAC DE\n  >b\nACDE\n",
+		">a\n>b\nACDE\n",
+	}
+	for _, s := range data {
+		tmpname, err := wrtTmp(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(tmpname)
+		s_opts := Options{DiffLenSeq: true, ZeroLenOK: true, InFormat: "fasta"}
+		want, err := Readfile(tmpname, &s_opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, o := range []Options{{NWorker: 3}, {Mmap: true}} {
+			s_opts.NWorker, s_opts.Mmap = o.NWorker, o.Mmap
+			got, err := Readfile(tmpname, &s_opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.NSeq() != want.NSeq() {
+				t.Fatalf("%q wanted %d seqs, got %d", s, want.NSeq(), got.NSeq())
+			}
+			for i, ss := range got.SeqSlc() {
+				w := want.SeqSlc()[i]
+				if string(ss.GetSeq()) != string(w.GetSeq()) || ss.Cmmt() != w.Cmmt() {
+					t.Fatalf("%q seq %d wanted %q %q got %q %q", s, i,
+						w.Cmmt(), w.GetSeq(), ss.Cmmt(), ss.GetSeq())
+				}
+			}
+			got.Close()
+		}
+		s_opts.NWorker, s_opts.Mmap = 0, false
+	}
+	tmpname, err := wrtTmp("\nACDE\n>a\nACDE\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpname)
+	for _, s_opts := range []Options{{}, {NWorker: 3}, {Mmap: true}} {
+		s_opts.InFormat = "fasta"
+		var perr *ParseError
+		if _, err := Readfile(tmpname, &s_opts); !errors.As(err, &perr) || perr.Line != 2 {
+			t.Fatal("wanted a parse error on line 2, got", err)
+		}
+	}
+}
+
+// TestParallelRange uses a range and reads from standard input, which
+// is read in one go and then parsed in parallel.
+func TestParallelRange(t *testing.T) {
+	s, want := manySeqs(3000)
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		io.WriteString(pw, s)
+		pw.Close()
+	}()
+	oldStdin := os.Stdin
+	os.Stdin = pr
+	defer func() { os.Stdin = oldStdin }()
+	seqgrp, err := Readfile("-", &Options{RangeStart: 2, RangeEnd: 9, NWorker: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seqgrp.NSeq() != len(want) {
+		t.Fatal("wanted", len(want), "seqs, got", seqgrp.NSeq())
+	}
+	for i, ss := range seqgrp.SeqSlc() {
+		if w := want[i][2:10]; string(ss.GetSeq()) != w {
+			t.Fatal("seq", i, "wanted", w, "got", string(ss.GetSeq()))
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// 17 Oct 2026
+// PHYLIP format, as used by RAxML, PhyML and the PHYLIP programs.
+// The first line has the number of sequences and the alignment length.
+// After that come the sequences, either sequential
+//
+//	2 12
+//	seq1  ACDEFGHIKL
+//	MN
+//	seq2  ACDEFGHIKL
+//	MN
+//
+// or interleaved, where only the first block has names
+//
+//	2 12
+//	seq1  ACDEFGHIKL
+//	seq2  ACDEFGHIKL
+//
+//	MN
+//	MN
+//
+// We only handle relaxed names. A name ends at white space and can be
+// any length. The old style of a name padded to exactly ten characters
+// works if the name is followed by a space.
+// There is nothing in the file to say if it is sequential or
+// interleaved. We try reading it as sequential and if the lengths do
+// not work out, we read it as interleaved.
+
+package seq
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	phylipMinName = 10 // Pad names to at least this, for old programs
+	phylipPerLine = 60 // residues per line in interleaved output
+)
+
+// phylipHeader parses the first line of a phylip file.
+func phylipHeader(line string) (nseq, nchar int, err error) {
+	const badHeader = "phylip header should be \"nseq nchar\", got \"%s\""
+	f := strings.Fields(line)
+	if len(f) < 2 {
+		return 0, 0, fmt.Errorf(badHeader, line)
+	}
+	if nseq, err = strconv.Atoi(f[0]); err != nil || nseq < 1 {
+		return 0, 0, fmt.Errorf(badHeader, line)
+	}
+	if nchar, err = strconv.Atoi(f[1]); err != nil || nchar < 0 {
+		return 0, 0, fmt.Errorf(badHeader, line)
+	}
+	return nseq, nchar, nil
+}
+
+// phylipSequential tries to read lines as a sequential phylip file.
+// It returns nil if the lengths do not come out right.
+func phylipSequential(lines []string, nseq, nchar int) *blockReader {
+	var blocks blockReader
+	i := 0
+	for iseq := 0; iseq < nseq; iseq++ {
+		if i >= len(lines) {
+			return nil
+		}
+		f := strings.Fields(lines[i])
+		name := f[0]
+		blocks.add(name, []byte(strings.Join(f[1:], "")))
+		for i++; len(blocks.byName[name]) < nchar && i < len(lines); i++ {
+			blocks.add(name, []byte(strings.Join(strings.Fields(lines[i]), "")))
+		}
+		if len(blocks.byName[name]) != nchar {
+			return nil
+		}
+	}
+	if i != len(lines) || len(blocks.order) != nseq {
+		return nil
+	}
+	return &blocks
+}
+
+// phylipInterleaved reads lines as an interleaved phylip file.
+func phylipInterleaved(lines []string, nseq, nchar int) (*blockReader, error) {
+	const bustLen = "phylip seq %s has length %d, header says %d"
+	if len(lines) < nseq {
+		return nil, fmt.Errorf("phylip file has %d lines, but %d sequences", len(lines), nseq)
+	}
+	var blocks blockReader
+	for _, line := range lines[:nseq] {
+		f := strings.Fields(line)
+		blocks.add(f[0], []byte(strings.Join(f[1:], "")))
+	}
+	if len(blocks.order) != nseq {
+		return nil, errors.New("phylip file has repeated sequence names")
+	}
+	for j, line := range lines[nseq:] {
+		name := blocks.order[j%nseq]
+		blocks.add(name, []byte(strings.Join(strings.Fields(line), "")))
+	}
+	for _, name := range blocks.order {
+		if l := len(blocks.byName[name]); l != nchar {
+			return nil, fmt.Errorf(bustLen, name, l, nchar)
+		}
+	}
+	return &blocks, nil
+}
+
+// ReadPhylip reads a sequential or interleaved phylip file with relaxed
+// names into seqgrp. The options work as for ReadFasta.
+func ReadPhylip(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	var lines []string
+	scanner := newScanner(rdr)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		return ErrNoSequences
+	}
+	nseq, nchar, err := phylipHeader(lines[0])
+	if err != nil {
+		return err
+	}
+	lines = lines[1:]
+	blocks := phylipSequential(lines, nseq, nchar)
+	if blocks == nil {
+		if blocks, err = phylipInterleaved(lines, nseq, nchar); err != nil {
+			return err
+		}
+	}
+	blocks.toGrp(seqgrp, "")
+	return finishGrp(seqgrp, s_opts)
+}
+
+// WritePhylip writes an alignment in relaxed phylip format. If
+// s_opts.Interleave is set, the alignment is written in blocks.
+// Names are the first word of each comment. If two sequences would get
+// the same name, nothing is written and we return an error. Rows have
+// to be aligned, so RmvGapsWrt is an error too.
+func WritePhylip(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error {
+	if s_opts.RmvGapsWrt {
+		return fmt.Errorf(alignedOnly, "phylip")
+	}
+	names, seqs, err := alnSeqs(seqgrp, false)
+	if err != nil {
+		return err
+	}
+	if err := uniqueNames(names, "phylip"); err != nil {
+		return err
+	}
+	width := nameWidth(names)
+	if width < phylipMinName {
+		width = phylipMinName
+	}
+	alnLen := seqs[0].Len()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%d %d\n", len(seqs), alnLen)
+	if !s_opts.Interleave {
+		for i, ss := range seqs {
+			fmt.Fprintf(bw, "%-*s %s\n", width, names[i], ss.seq)
+		}
+		return bw.Flush()
+	}
+	for start := 0; start < alnLen; start += phylipPerLine {
+		end := start + phylipPerLine
+		if end > alnLen {
+			end = alnLen
+		}
+		if start > 0 {
+			bw.WriteByte('\n')
+		}
+		for i, ss := range seqs {
+			if start == 0 {
+				fmt.Fprintf(bw, "%-*s ", width, names[i])
+			}
+			fmt.Fprintf(bw, "%s\n", ss.seq[start:end])
+		}
+	}
+	return bw.Flush()
+}
+
+// isPhylip looks at the start of a file and says if it looks like
+// phylip format. The first line has to be two numbers.
+func isPhylip(head []byte) bool {
+	line := strings.TrimSpace(string(head))
+	if i := strings.IndexByte(line, '\n'); i != -1 {
+		line = line[:i]
+	}
+	_, _, err := phylipHeader(line)
+	return err == nil
+}
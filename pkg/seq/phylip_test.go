@@ -0,0 +1,185 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+var (
+	phySeq = `3 12
+seq1   ACDEFGHIKL
+MN
+a_long_name_here ACDEFG
+HIKL-N
+s3     acdefghiklmn
+`
+	phyInter = `3 12
+seq1             ACDEFG
+a_long_name_here ACDEFG
+s3               acdefg
+
+HIKLMN
+HIKL-N
+hiklmn
+`
+	nexus = `#NEXUS
+[ a comment [nested] here ]
+BEGIN TAXA;
+  DIMENSIONS NTAX=3;
+END;
+begin characters;
+  dimensions nchar=12;
+  format datatype=protein gap=~ matchchar=. interleave;
+  matrix
+    seq1              ACDEFG
+    'a long name'     ......  [ comment in the matrix ]
+    s3                acdefg
+
+    seq1              HIKLMN
+    'a long name'     ....~.
+    s3                hiklmn
+  ;
+end;
+`
+)
+
+// checkPhy checks the sequences that come from all the test data
+func checkPhy(seqgrp *SeqGrp, name2 string, t *testing.T) {
+	want := []string{"ACDEFGHIKLMN", "ACDEFGHIKL-N", "acdefghiklmn"}
+	if seqgrp.NSeq() != len(want) {
+		t.Fatal("wanted 3 seqs, got", seqgrp.NSeq())
+	}
+	for i, ss := range seqgrp.SeqSlc() {
+		if s := string(ss.GetSeq()); s != want[i] {
+			t.Fatalf("seq %d wanted %s got %s", i, want[i], s)
+		}
+	}
+	if c := seqgrp.SeqSlc()[1].Cmmt(); c != name2 {
+		t.Fatal("name wanted", name2, "got", c)
+	}
+}
+
+// TestPhylipRead reads sequential and interleaved files
+func TestPhylipRead(t *testing.T) {
+	for _, s := range []string{phySeq, phyInter} {
+		var seqgrp SeqGrp
+		if err := ReadPhylip(strings.NewReader(s), &seqgrp, &Options{}); err != nil {
+			t.Fatal(err)
+		}
+		checkPhy(&seqgrp, "a_long_name_here", t)
+	}
+	var seqgrp SeqGrp
+	broken := "2 5\na ACDEF\nb ACDE\n"
+	if err := ReadPhylip(strings.NewReader(broken), &seqgrp, &Options{}); err == nil {
+		t.Fatal("should fail on short sequence")
+	}
+}
+
+// TestNexusRead reads a file with comments, quotes, match characters
+// and a strange gap character.
+func TestNexusRead(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadNexus(strings.NewReader(nexus), &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	checkPhy(&seqgrp, "a long name", t)
+}
+
+// TestPhylipNexusRoundTrip writes and reads back, with and without
+// interleaving.
+func TestPhylipNexusRoundTrip(t *testing.T) {
+	long := strings.Repeat("ACDEFGHIKL", 13)
+	ss := []string{long, strings.Replace(long, "E", "-", -1), strings.ToLower(long)}
+	for _, interleave := range []bool{false, true} {
+		for _, format := range []string{"phylip", "nexus"} {
+			var b bytes.Buffer
+			var err error
+			s_opts := &Options{Interleave: interleave}
+			seqgrp := Str2SeqGrp(ss, "x")
+			if format == "phylip" {
+				err = WritePhylip(&b, seqgrp, s_opts)
+			} else {
+				err = WriteNexus(&b, seqgrp, s_opts)
+			}
+			if err != nil {
+				t.Fatal(format, err)
+			}
+			tmpname, err := wrtTmp(b.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(tmpname)
+			back, err := Readfile(tmpname, &Options{})
+			if err != nil {
+				t.Fatal(format, "interleave", interleave, err, "\n", b.String())
+			}
+			for i, s := range back.SeqSlc() {
+				if string(s.GetSeq()) != ss[i] {
+					t.Fatal(format, "seq", i, "got", string(s.GetSeq()))
+				}
+			}
+		}
+	}
+}
+
+// TestRepeatedNames wants an error if two sequences would get the same
+// name, here from the first word of the comment.
+func TestRepeatedNames(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadFasta(strings.NewReader("> x one\nAC\n> x two\nAD\n"), &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, wrt := range []func(*bytes.Buffer, *SeqGrp, *Options) error{
+		func(w *bytes.Buffer, g *SeqGrp, o *Options) error { return WritePhylip(w, g, o) },
+		func(w *bytes.Buffer, g *SeqGrp, o *Options) error { return WriteNexus(w, g, o) },
+	} {
+		var b bytes.Buffer
+		if err := wrt(&b, &seqgrp, &Options{}); err == nil {
+			t.Fatal("repeated name should fail, wrote\n", b.String())
+		}
+	}
+}
+
+// TestAlignedOnly asks for gaps to be removed, which phylip and nexus
+// cannot do, since their rows must all be the same length.
+func TestAlignedOnly(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AC-D", "ACED"}, "s")
+	for name, wrt := range map[string]func(*bytes.Buffer, *SeqGrp, *Options) error{
+		"phylip": func(w *bytes.Buffer, g *SeqGrp, o *Options) error { return WritePhylip(w, g, o) },
+		"nexus":  func(w *bytes.Buffer, g *SeqGrp, o *Options) error { return WriteNexus(w, g, o) },
+	} {
+		var b bytes.Buffer
+		if err := wrt(&b, seqgrp, &Options{RmvGapsWrt: true}); err == nil || b.Len() != 0 {
+			t.Fatal(name, "with RmvGapsWrt should fail without writing, wrote\n", b.String())
+		}
+		if err := wrt(&b, seqgrp, &Options{}); err != nil {
+			t.Fatal(name, err)
+		}
+	}
+}
+
+// TestNexusQuotedSemicolon writes a name with ";" in it, which has to
+// be quoted, and reads it back.
+func TestNexusQuotedSemicolon(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadFasta(strings.NewReader(">a;b\nACD\n>c\nAC-\n"), &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteNexus(&b, &seqgrp, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var back SeqGrp
+	if err := ReadNexus(strings.NewReader(b.String()), &back, &Options{}); err != nil {
+		t.Fatal(err, "\n", b.String())
+	}
+	if back.NSeq() != 2 || back.SeqSlc()[0].Cmmt() != "a;b" || string(back.SeqSlc()[1].GetSeq()) != "AC-" {
+		t.Fatal("misread\n", b.String())
+	}
+}
@@ -0,0 +1,353 @@
+// 17 Oct 2026
+// Pseudocounts. With a few sequences, raw frequencies are mostly
+// zeros and the entropy and KL divergence jump about. A Prior turns the
+// weighted residue counts in a column into probabilities, adding what
+// we expected to see before looking. There are four kinds:
+//   uniform      n_a + alpha for every symbol (alpha 1 is Laplace)
+//   background   n_a + A q_a, q from BLOSUM62 for proteins
+//   blosum62     n_a + A g_a, g_a = sum_b f_b q(a|b) from BLOSUM62
+//                target frequencies, as in PSI-BLAST
+//   dirichlet    a Dirichlet mixture from a .9comp file (Sjölander
+//                et al. (1996) CABIOS 12, 327-345)
+// SetPrior gives a SeqGrp a prior, so NewProfile, Entropy and Compat
+// use it. Profile.WithPrior does the same for a profile already built,
+// for example from an Accumulator.
+
+package seq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// A Prior adds pseudocounts to the residue counts of one column.
+type Prior interface {
+	// Symbols are the residues the prior knows about, upper case.
+	Symbols() string
+	// Estimate turns counts n, in the order of Symbols, into
+	// probabilities p, which add up to one.
+	Estimate(n, p []float64)
+}
+
+// sumOf adds up a slice.
+func sumOf(x []float64) (sum float64) {
+	for _, v := range x {
+		sum += v
+	}
+	return sum
+}
+
+// uniformPrior adds alpha to every count.
+type uniformPrior struct {
+	syms  string
+	alpha float64
+}
+
+// NewUniformPrior adds alpha to the count of each symbol in a, so 1 is
+// Laplace's rule.
+func NewUniformPrior(a *Alphabet, alpha float64) Prior {
+	return &uniformPrior{syms: a.symbols, alpha: alpha}
+}
+
+func (u *uniformPrior) Symbols() string { return u.syms }
+
+func (u *uniformPrior) Estimate(n, p []float64) {
+	denom := sumOf(n) + float64(len(n))*u.alpha
+	for i := range n {
+		p[i] = (n[i] + u.alpha) / denom
+	}
+}
+
+// bgPrior adds weight pseudocounts, shared by background frequencies.
+type bgPrior struct {
+	syms   string
+	bg     []float64
+	weight float64
+}
+
+// NewBackgroundPrior adds weight pseudocounts to each column, shared
+// out by the background frequencies. For proteins, these are from
+// BLOSUM62. For anything else, every symbol in a is equally likely.
+func NewBackgroundPrior(a *Alphabet, weight float64) Prior {
+	if a.stype == Protein {
+		return &bgPrior{syms: blosumOrder, bg: blosumBg[:], weight: weight}
+	}
+	bg := make([]float64, len(a.symbols))
+	for i := range bg {
+		bg[i] = 1 / float64(len(bg))
+	}
+	return &bgPrior{syms: a.symbols, bg: bg, weight: weight}
+}
+
+func (b *bgPrior) Symbols() string { return b.syms }
+
+func (b *bgPrior) Estimate(n, p []float64) {
+	denom := sumOf(n) + b.weight
+	for i := range n {
+		p[i] = (n[i] + b.weight*b.bg[i]) / denom
+	}
+}
+
+// substPrior is the PSI-BLAST pseudocount.
+type substPrior struct {
+	cond   [20][20]float64 // cond[b][a] is q(a|b)
+	weight float64
+}
+
+// NewBlosumPrior adds weight pseudocounts to each column, shared out
+// by what BLOSUM62 says the residues seen would turn into. The target
+// frequencies are worked out from the scores as q_a q_b 2^(s/2), so
+// they are a little out from the rounding of the scores. It is only
+// for proteins.
+func NewBlosumPrior(weight float64) Prior {
+	s := &substPrior{weight: weight}
+	for b := range s.cond {
+		var sum float64
+		for a := range s.cond[b] {
+			q := blosumBg[a] * blosumBg[b] * math.Pow(2, float64(blosum62[a][b])/2)
+			s.cond[b][a] = q
+			sum += q
+		}
+		for a := range s.cond[b] {
+			s.cond[b][a] /= sum
+		}
+	}
+	return s
+}
+
+func (s *substPrior) Symbols() string { return blosumOrder }
+
+func (s *substPrior) Estimate(n, p []float64) {
+	nsum := sumOf(n)
+	for a := range p {
+		g := blosumBg[a] // With no counts, there is only the background
+		if nsum > 0 {
+			g = 0
+			for b, nb := range n {
+				g += nb / nsum * s.cond[b][a]
+			}
+		}
+		p[a] = (n[a] + s.weight*g) / (nsum + s.weight)
+	}
+}
+
+// Dirichlet is a Dirichlet mixture prior.
+type Dirichlet struct {
+	syms  string
+	mix   []float64   // mixture coefficient of each component
+	alpha [][]float64 // alpha[j][a] for component j, symbol a
+	asum  []float64   // sum of alpha[j]
+}
+
+// ReadDirichlet reads a Dirichlet mixture in .9comp format, as from
+// the UCSC SAM package. We use the Order, Mixture and Alpha lines.
+// The first number after Alpha= is the sum of the rest, so we skip it.
+func ReadDirichlet(rdr io.Reader) (*Dirichlet, error) {
+	const badLine = "dirichlet line %d: %w"
+	d := new(Dirichlet)
+	scanner := bufio.NewScanner(rdr)
+	for iline := 1; scanner.Scan(); iline++ {
+		key, val, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		switch key {
+		case "Order":
+			d.syms = strings.ToUpper(strings.Join(strings.Fields(val), ""))
+		case "Mixture":
+			nums, err := parseFloats(val)
+			if err == nil && len(nums) != 1 {
+				err = fmt.Errorf("wanted one number, got %d", len(nums))
+			}
+			if err != nil {
+				return nil, fmt.Errorf(badLine, iline, err)
+			}
+			d.mix = append(d.mix, nums[0])
+		case "Alpha":
+			nums, err := parseFloats(val)
+			if err == nil && len(nums) < 2 {
+				err = fmt.Errorf("wanted the sum and the alphas, got %d numbers", len(nums))
+			}
+			if err != nil {
+				return nil, fmt.Errorf(badLine, iline, err)
+			}
+			d.alpha = append(d.alpha, nums[1:])
+			d.asum = append(d.asum, sumOf(nums[1:]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	switch {
+	case d.syms == "":
+		return nil, fmt.Errorf("dirichlet mixture has no Order line")
+	case len(d.alpha) == 0 || len(d.alpha) != len(d.mix):
+		return nil, fmt.Errorf("dirichlet mixture has %d Mixture and %d Alpha lines", len(d.mix), len(d.alpha))
+	}
+	for j, a := range d.alpha {
+		if len(a) != len(d.syms) {
+			return nil, fmt.Errorf("dirichlet component %d has %d alphas for %d symbols", j, len(a), len(d.syms))
+		}
+	}
+	return d, nil
+}
+
+// parseFloats reads the numbers in s.
+func parseFloats(s string) ([]float64, error) {
+	var nums []float64
+	for _, f := range strings.Fields(s) {
+		x, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, x)
+	}
+	return nums, nil
+}
+
+// Symbols are the residues, from the Order line.
+func (d *Dirichlet) Symbols() string { return d.syms }
+
+// Estimate is the posterior mean. Each component is weighted by its
+// mixture coefficient times the probability of the counts under it,
+//
+//	p_a = sum_j P(j|n) (n_a + alpha_ja) / (N + |alpha_j|)
+//
+// The probabilities are worked out with logarithms, so deep columns do
+// not overflow.
+func (d *Dirichlet) Estimate(n, p []float64) {
+	nsum := sumOf(n)
+	lpost := make([]float64, len(d.mix))
+	lmax := math.Inf(-1)
+	lg := func(x float64) float64 { y, _ := math.Lgamma(x); return y }
+	for j, alpha := range d.alpha {
+		l := math.Log(d.mix[j]) + lg(d.asum[j]) - lg(nsum+d.asum[j])
+		for a, na := range n {
+			l += lg(na+alpha[a]) - lg(alpha[a])
+		}
+		lpost[j] = l
+		lmax = max(lmax, l)
+	}
+	var norm float64
+	for j := range lpost {
+		lpost[j] = math.Exp(lpost[j] - lmax)
+		norm += lpost[j]
+	}
+	clear(p)
+	for j, alpha := range d.alpha {
+		w := lpost[j] / norm / (nsum + d.asum[j])
+		for a, na := range n {
+			p[a] += w * (na + alpha[a])
+		}
+	}
+}
+
+// PriorByName makes a prior from a name, as for the -prior option.
+// "uniform", "background" and "blosum62" can be followed by ":" and
+// the pseudocount (alpha for uniform, the total weight for the
+// others). The defaults are 1, the size of the alphabet and 10 (as in
+// PSI-BLAST). Anything else is the name of a .9comp file. a is needed
+// for all of them, and blosum62 and .9comp files must be for proteins,
+// with the symbols of a. "" gives no prior.
+func PriorByName(name string, a *Alphabet) (Prior, error) {
+	kind, num, hasNum := strings.Cut(name, ":")
+	x := math.NaN()
+	if hasNum {
+		var err error
+		if x, err = strconv.ParseFloat(num, 64); err != nil || x <= 0 {
+			return nil, fmt.Errorf("prior %q needs a positive pseudocount", name)
+		}
+	}
+	orDefault := func(d float64) float64 {
+		if math.IsNaN(x) {
+			return d
+		}
+		return x
+	}
+	needAlpha := func() error {
+		if a == nil {
+			return fmt.Errorf("prior %q needs an alphabet, the sequence type could not be guessed", kind)
+		}
+		return nil
+	}
+	switch kind {
+	case "":
+		return nil, nil
+	case "uniform":
+		if err := needAlpha(); err != nil {
+			return nil, err
+		}
+		return NewUniformPrior(a, orDefault(1)), nil
+	case "background":
+		if err := needAlpha(); err != nil {
+			return nil, err
+		}
+		return NewBackgroundPrior(a, orDefault(float64(a.Size()))), nil
+	case "blosum62":
+		if err := needAlpha(); err != nil {
+			return nil, err
+		}
+		prior := NewBlosumPrior(orDefault(10))
+		if err := CheckPrior(prior, a); err != nil {
+			return nil, fmt.Errorf("prior %q: %w", kind, err)
+		}
+		return prior, nil
+	}
+	if err := needAlpha(); err != nil {
+		return nil, err
+	}
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("prior %q is not uniform, background, blosum62 or a .9comp file: %w", name, err)
+	}
+	defer fp.Close()
+	d, err := ReadDirichlet(fp)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if err := CheckPrior(d, a); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+// CheckPrior says if prior can be used for sequences in alphabet a.
+// BLOSUM62 and Dirichlet mixtures are only for proteins. Every prior
+// must know the same symbols as a, otherwise there are rows which do
+// not fit the logarithm base, a.Size(), and the entropy can go over
+// one. Use it for priors from NewBlosumPrior or ReadDirichlet.
+func CheckPrior(prior Prior, a *Alphabet) error {
+	if a == nil {
+		return fmt.Errorf("prior needs an alphabet, the sequence type could not be guessed")
+	}
+	switch prior.(type) {
+	case *substPrior, *Dirichlet:
+		if a.stype != Protein {
+			return fmt.Errorf("prior is for proteins, alphabet is %s", a.name)
+		}
+	}
+	syms := prior.Symbols()
+	if len(syms) != a.Size() {
+		return fmt.Errorf("prior has %d symbols, alphabet %s has %d", len(syms), a.name, a.Size())
+	}
+	for i := range len(syms) {
+		if !a.IsSymbol(syms[i]) {
+			return fmt.Errorf("prior symbol %c is not in alphabet %s", syms[i], a.name)
+		}
+	}
+	return nil
+}
+
+// SetPrior says which pseudocounts NewProfile, and so Entropy and
+// Compat, should use. nil means none, just the frequencies. UsageFrac
+// does not use it.
+func (seqgrp *SeqGrp) SetPrior(prior Prior) {
+	seqgrp.stale()
+	seqgrp.prior = prior
+}
@@ -0,0 +1,213 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// dnaGrp is three sequences with the dna alphabet. Site 0 is all A,
+// site 1 is A, A, C and site 2 is all gaps.
+func dnaGrp(t *testing.T) *SeqGrp {
+	seqgrp := Str2SeqGrp([]string{"AA-", "AA-", "AC-"})
+	dna, err := AlphabetByName("dna")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqgrp.SetAlphabet(dna)
+	return seqgrp
+}
+
+// TestUniformPrior adds one to each of four bases. Three A give
+// (3+1)/(3+4) for A and 1/7 for the others, which has some entropy.
+// Background for DNA with the default weight of four is the same.
+// Sites with only gaps stay as they are.
+func TestUniformPrior(t *testing.T) {
+	seqgrp := dnaGrp(t)
+	entropy := make([]float32, 3)
+	newProfile(t, seqgrp, false).Entropy(entropy)
+	if entropy[0] != 0 {
+		t.Fatal("no prior, conserved site should have zero entropy, got", entropy[0])
+	}
+	for _, name := range []string{"uniform", "uniform:1", "background"} {
+		prior, err := PriorByName(name, seqgrp.Alphabet())
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqgrp.SetPrior(prior)
+		p := newProfile(t, seqgrp, false)
+		if !roughEql(p.Freq('A', 0), 4./7) || !roughEql(p.Freq('G', 0), 1./7) {
+			t.Fatal(name, "wanted 4/7 and 1/7, got", p.Freq('A', 0), p.Freq('G', 0))
+		}
+		if p.Count('G', 0) != 0 || p.Weight('A', 0) != 3 {
+			t.Fatal(name, "counts and weights should not have pseudocounts")
+		}
+		var sum float32
+		for _, c := range []byte("ACGT") {
+			sum += p.Freq(c, 1)
+		}
+		if !roughEql(sum, 1) {
+			t.Fatal(name, "frequencies add up to", sum)
+		}
+		p.Entropy(entropy)
+		if entropy[0] <= 0 || entropy[2] != 0 {
+			t.Fatal(name, "entropy", entropy)
+		}
+	}
+	for _, name := range []string{"uniform:0", "uniform:x", "no/such/file.9comp"} {
+		if _, err := PriorByName(name, seqgrp.Alphabet()); err == nil {
+			t.Fatal("prior", name, "should fail")
+		}
+	}
+}
+
+// TestPriorCompat takes the reference away before the pseudocounts go
+// in. At site 1, the C of the third sequence is not seen in the
+// others, so it is 0 without a prior and 1/(2+4) with a uniform one.
+func TestPriorCompat(t *testing.T) {
+	seqgrp := dnaGrp(t)
+	ref := seqgrp.SeqSlc()[2].GetSeq()
+	if c := compat(t, seqgrp, ref, false); c[1] != 0 {
+		t.Fatal("without a prior, wanted 0, got", c[1])
+	}
+	seqgrp.SetPrior(NewUniformPrior(seqgrp.Alphabet(), 1))
+	c := compat(t, seqgrp, ref, false)
+	if !roughEql(c[0], 3./6) || !roughEql(c[1], 1./6) || c[2] != 0 {
+		t.Fatal("with a prior, wanted 1/2, 1/6, 0, got", c)
+	}
+}
+
+// TestBlosumPrior looks at a column of W. Pseudocounts should go more
+// to Y and F, which BLOSUM62 likes to swap with W, than to A.
+func TestBlosumPrior(t *testing.T) {
+	prior := NewBlosumPrior(10)
+	syms := prior.Symbols()
+	n, p := make([]float64, len(syms)), make([]float64, len(syms))
+	n[strings.IndexByte(syms, 'W')] = 5
+	prior.Estimate(n, p)
+	var sum float64
+	for _, x := range p {
+		sum += x
+	}
+	if sum < 0.999 || sum > 1.001 {
+		t.Fatal("probabilities add up to", sum)
+	}
+	pr := func(c byte) float64 { return p[strings.IndexByte(syms, c)] }
+	if pr('W') < 5./15 || pr('Y') <= pr('A') || pr('F') <= pr('A') {
+		t.Fatal("W", pr('W'), "Y", pr('Y'), "F", pr('F'), "A", pr('A'))
+	}
+}
+
+// dirichlet1 is a one component mixture with every alpha one, which
+// is the same as a uniform prior.
+const dirichlet1 = `Name = test
+Order = A C G T
+Name = component 1
+Mixture= 1.0
+Alpha= 4.0 1.0 1.0 1.0 1.0
+Comment= nothing
+`
+
+// protGrp is dnaGrp with the protein alphabet.
+func protGrp(t *testing.T) *SeqGrp {
+	seqgrp := Str2SeqGrp([]string{"AA-", "AA-", "AC-"})
+	prot, err := AlphabetByName("protein")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqgrp.SetAlphabet(prot)
+	return seqgrp
+}
+
+// protDirichlet1 is dirichlet1 for the twenty amino acids.
+var protDirichlet1 = strings.NewReplacer(
+	"A C G T", "A C D E F G H I K L M N P Q R S T V W Y",
+	"4.0 1.0 1.0 1.0 1.0", "20.0"+strings.Repeat(" 1.0", 20)).Replace(dirichlet1)
+
+// TestDirichlet reads a mixture from a file, checks it against the
+// uniform prior and then tries a two component mixture, where a column
+// of A should be explained by the component which likes A.
+func TestDirichlet(t *testing.T) {
+	fname, err := common.WrtTemp(protDirichlet1)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	seqgrp := protGrp(t)
+	prior, err := PriorByName(fname, seqgrp.Alphabet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqgrp.SetPrior(prior)
+	if f := newProfile(t, seqgrp, false).Freq('A', 0); !roughEql(f, 4./23) {
+		t.Fatal("one component, wanted 4/23, got", f)
+	}
+
+	two := dirichlet1 + `Mixture= 1.0
+Alpha= 23.0 20.0 1.0 1.0 1.0
+`
+	two = strings.Replace(two, "Mixture= 1.0", "Mixture= 0.5", 2)
+	d, err := ReadDirichlet(strings.NewReader(two))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, p := []float64{10, 0, 0, 0}, make([]float64, 4)
+	d.Estimate(n, p)
+	if p[0] < 0.8 || p[1] != p[2] {
+		t.Fatal("column of A wanted mostly A, got", p)
+	}
+
+	for _, bad := range []string{
+		strings.Replace(dirichlet1, "Order", "Odor", 1),
+		strings.Replace(dirichlet1, "Mixture= 1.0", "Mixture= 1.0 2.0", 1),
+		strings.Replace(dirichlet1, "Alpha= 4.0 1.0 1.0 1.0 1.0", "Alpha= 3.0 1.0 1.0 1.0", 1),
+		strings.Replace(dirichlet1, "Alpha= 4.0", "Alpha= four", 1),
+		dirichlet1 + "Mixture= 1.0\n",
+	} {
+		if _, err := ReadDirichlet(strings.NewReader(bad)); err == nil {
+			t.Fatal("should fail on\n", bad)
+		}
+	}
+}
+
+// TestPriorAlphabet wants an error when the prior does not fit the
+// alphabet. On DNA, BLOSUM62 and a protein mixture would add rows the
+// logarithm base does not know about. A four letter mixture does not
+// fit proteins and nothing fits if the type could not be guessed.
+func TestPriorAlphabet(t *testing.T) {
+	protFile, err := common.WrtTemp(protDirichlet1)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(protFile)
+	dnaFile, err := common.WrtTemp(dirichlet1)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(dnaFile)
+	dna, prot := dnaGrp(t).Alphabet(), protGrp(t).Alphabet()
+	for _, tc := range []struct {
+		name string
+		a    *Alphabet
+	}{
+		{"blosum62", dna}, {"blosum62:5", dna}, {protFile, dna}, {dnaFile, dna},
+		{dnaFile, prot}, {"blosum62", nil}, {protFile, nil},
+	} {
+		if _, err := PriorByName(tc.name, tc.a); err == nil {
+			t.Fatal("prior", tc.name, "should not fit alphabet", tc.a)
+		}
+	}
+	for _, name := range []string{"blosum62", protFile} {
+		if _, err := PriorByName(name, prot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := CheckPrior(NewBlosumPrior(10), dna); err == nil {
+		t.Fatal("blosum62 should not fit dna")
+	}
+}
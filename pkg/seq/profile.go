@@ -0,0 +1,352 @@
+// 17 Oct 2026
+// A Profile is the per-site statistics of an alignment, counts and
+// frequencies of each symbol in each column. UsageSite and UsageFrac
+// keep these inside the SeqGrp and UsageFrac overwrites the counts with
+// frequencies for whichever gap policy it is called with first, so
+// asking for entropy with and without gaps on one SeqGrp quietly gave
+// the same numbers. A Profile is built for one gap policy, keeps the
+// raw counts next to the frequencies and is never changed after
+// NewProfile returns, so several can be used at once.
+
+package seq
+
+import (
+	"github.com/andrew-torda/matrix"
+	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// Profile holds counts and frequencies for an alignment. Rows are
+// symbols, in the order of Symbols(), and columns are sites.
+type Profile struct {
+	counts      *matrix.IMatrix2d // plain counts, one per symbol
+	weights     *matrix.FMatrix2d // counts with quality and terminal gap weights
+	freqs       *matrix.FMatrix2d // frequencies, normalised as by UsageFrac
+	gapFrac     []float32         // weighted fraction of gaps, nil if no gaps
+	revmap      []uint8           // symbol in each row
+	mapping     [MaxSym]uint8     // row of each symbol, badMap if not seen
+	remap       [256]uint8        // the alphabet's remapping when built
+	prior       Prior             // pseudocounts in freqs, or nil
+	priorRow    []int             // row of each prior symbol, -1 if none
+	nseq        int
+	ncol        int
+	logbase     int
+	gapsAreChar bool
+}
+
+// NewProfile counts the symbols in seqgrp and works out frequencies.
+// If gapsAreChar is false, symbol frequencies are fractions of the
+// residues in each column and gaps are ignored. Weights from
+// SetQualWeight and SetTermGapWeight, the alphabet and the number of
+// symbols are taken from seqgrp as they are now. Changing seqgrp later
+// does not change the profile. With SetPrior, the frequencies have
+// pseudocounts, but counts and weights are as seen. It is an error if
+// the sequences changed after SetSeqWeights.
+func NewProfile(seqgrp *SeqGrp, gapsAreChar bool) (*Profile, error) {
+	if len(seqgrp.revmap) == 0 {
+		seqgrp.mapsyms()
+	}
+	nrow, ncol := len(seqgrp.revmap), seqgrp.GetLen()
+	p := &Profile{
+		counts:      matrix.NewIMatrix2d(nrow, ncol),
+		weights:     matrix.NewFMatrix2d(nrow, ncol),
+		freqs:       matrix.NewFMatrix2d(nrow, ncol),
+		revmap:      append([]uint8(nil), seqgrp.revmap...),
+		mapping:     seqgrp.mapping,
+		remap:       *seqgrp.remapTable(),
+		nseq:        seqgrp.NSeq(),
+		ncol:        ncol,
+		logbase:     seqgrp.GetLogBase(gapsAreChar),
+		gapsAreChar: gapsAreChar,
+	}
+	if err := seqgrp.tally(p.counts.Mat, p.weights.Mat); err != nil {
+		return nil, err
+	}
+	p.finish()
+	if seqgrp.prior != nil {
+		p.applyPrior(seqgrp.prior)
+	}
+	return p, nil
+}
+
+// WithPrior is a copy of p, with the frequencies worked out again using
+// prior, or without pseudocounts if prior is nil. It is for profiles
+// from an Accumulator, which has no SetPrior.
+func (p *Profile) WithPrior(prior Prior) *Profile {
+	q := *p
+	q.freqs = matrix.NewFMatrix2d(len(q.revmap), q.ncol)
+	q.prior, q.priorRow = nil, nil
+	q.finish()
+	if prior != nil {
+		q.applyPrior(prior)
+	}
+	return &q
+}
+
+// applyPrior adds a row for each prior symbol not seen and redoes the
+// frequencies of the residues the prior knows, which share their part
+// of a column as Estimate says. Anything else, like X, keeps its plain
+// fraction. Pseudocounts never go to gaps, so the gap row is as before,
+// whatever gapsAreChar says. Columns without residues are left alone.
+func (p *Profile) applyPrior(prior Prior) {
+	syms := prior.Symbols()
+	var used [MaxSym]bool
+	for _, c := range p.revmap {
+		used[c] = true
+	}
+	for i := 0; i < len(syms); i++ {
+		if c := p.remap[syms[i]]; c < MaxSym {
+			used[c] = true
+		}
+	}
+	oldWt, oldCnt, oldFreq, oldMap := p.weights, p.counts, p.freqs, p.mapping
+	p.revmap = nil
+	for i := range p.mapping {
+		p.mapping[i] = badMap
+	}
+	for c := range used {
+		if used[c] {
+			p.mapping[c] = uint8(len(p.revmap))
+			p.revmap = append(p.revmap, uint8(c))
+		}
+	}
+	nrow := len(p.revmap)
+	p.counts = matrix.NewIMatrix2d(nrow, p.ncol)
+	p.weights = matrix.NewFMatrix2d(nrow, p.ncol)
+	p.freqs = matrix.NewFMatrix2d(nrow, p.ncol)
+	for irow, c := range p.revmap {
+		if old := oldMap[c]; old != badMap {
+			copy(p.counts.Mat[irow], oldCnt.Mat[old])
+			copy(p.weights.Mat[irow], oldWt.Mat[old])
+			copy(p.freqs.Mat[irow], oldFreq.Mat[old])
+		}
+	}
+	p.prior = prior
+	p.priorRow = make([]int, len(syms))
+	isPrior := make([]bool, nrow)
+	for i := range p.priorRow {
+		p.priorRow[i] = p.row(syms[i])
+		if p.priorRow[i] != -1 {
+			isPrior[p.priorRow[i]] = true
+		}
+	}
+
+	gappos := int(p.mapping[GapChar])
+	n, est := make([]float64, len(syms)), make([]float64, len(syms))
+	for icol := 0; icol < p.ncol; icol++ {
+		var nres, ngap float64
+		for irow, row := range p.weights.Mat {
+			if irow == gappos {
+				ngap = float64(row[icol])
+			} else {
+				nres += float64(row[icol])
+			}
+		}
+		if nres == 0 {
+			continue
+		}
+		nprior := p.priorCounts(icol, n)
+		prior.Estimate(n, est)
+		total := nres
+		if p.gapsAreChar {
+			total += ngap
+		}
+		for i, irow := range p.priorRow {
+			if irow != -1 {
+				p.freqs.Mat[irow][icol] = float32(est[i] * nprior / total)
+			}
+		}
+	}
+}
+
+// priorCounts puts the weighted counts of the prior's symbols at icol
+// into n and returns their sum.
+func (p *Profile) priorCounts(icol int, n []float64) (sum float64) {
+	for i, irow := range p.priorRow {
+		n[i] = 0
+		if irow != -1 {
+			n[i] = float64(p.weights.Mat[irow][icol])
+		}
+		sum += n[i]
+	}
+	return sum
+}
+
+// finish works out the frequencies and gap fractions from the weights.
+func (p *Profile) finish() {
+	for i, row := range p.weights.Mat {
+		copy(p.freqs.Mat[i], row)
+	}
+	gappos := p.mapping[GapChar]
+	normalise(p.freqs.Mat, gappos, p.gapsAreChar)
+	if gappos == badMap {
+		return
+	}
+	p.gapFrac = make([]float32, p.ncol)
+	for icol := range p.gapFrac {
+		var total float32
+		for irow := range p.weights.Mat {
+			total += p.weights.Mat[irow][icol]
+		}
+		p.gapFrac[icol] = 1 // Nothing counted, not even a weighted residue
+		if total != 0 {
+			p.gapFrac[icol] = p.weights.Mat[gappos][icol] / total
+		}
+	}
+}
+
+// NSeq is the number of sequences counted.
+func (p *Profile) NSeq() int { return p.nseq }
+
+// Len is the number of sites.
+func (p *Profile) Len() int { return p.ncol }
+
+// GapsAreChar says which gap policy the frequencies are for.
+func (p *Profile) GapsAreChar() bool { return p.gapsAreChar }
+
+// LogBase is the base for logarithms, as from SeqGrp.GetLogBase.
+func (p *Profile) LogBase() int { return p.logbase }
+
+// Symbols returns the symbol for each row, after remapping.
+func (p *Profile) Symbols() []byte { return append([]byte(nil), p.revmap...) }
+
+// row returns the row for symbol c, or -1 if it was never seen.
+func (p *Profile) row(c byte) int {
+	if m := p.mapping[p.remap[c]]; m != badMap {
+		return int(m)
+	}
+	return -1
+}
+
+// GapMapping is the row for gaps, as SeqGrp.GetMapping(GapChar) gives
+// for EntropyFromArray.
+func (p *Profile) GapMapping() uint8 { return p.mapping[GapChar] }
+
+// Count is the number of times c is found at site icol, without any
+// weights.
+func (p *Profile) Count(c byte, icol int) int32 {
+	if r := p.row(c); r != -1 {
+		return p.counts.Mat[r][icol]
+	}
+	return 0
+}
+
+// Weight is the weighted count of c at site icol. Without weights, it
+// is the same as Count.
+func (p *Profile) Weight(c byte, icol int) float32 {
+	if r := p.row(c); r != -1 {
+		return p.weights.Mat[r][icol]
+	}
+	return 0
+}
+
+// Freq is the frequency of c at site icol.
+func (p *Profile) Freq(c byte, icol int) float32 {
+	if r := p.row(c); r != -1 {
+		return p.freqs.Mat[r][icol]
+	}
+	return 0
+}
+
+// Freqs returns a copy of the frequency matrix, one row per symbol.
+func (p *Profile) Freqs() *matrix.FMatrix2d {
+	nrow, ncol := p.freqs.Size()
+	m := matrix.NewFMatrix2d(nrow, ncol)
+	for i, row := range p.freqs.Mat {
+		copy(m.Mat[i], row)
+	}
+	return m
+}
+
+// GapFrac returns the fraction of gaps at each site, the same for
+// either gap policy. It is a copy. If there are no gaps, it is nil. A
+// site where nothing counts, like one with only terminal gaps of
+// weight zero, is taken as all gaps.
+func (p *Profile) GapFrac() []float32 {
+	if p.gapFrac == nil {
+		return nil
+	}
+	return append([]float32(nil), p.gapFrac...)
+}
+
+// SiteNeff is the weighted number of sequences with a residue at each
+// site. With weights from IdentityWeights, it is the effective number
+// of sequences there. Without weights, it is a plain count.
+func (p *Profile) SiteNeff() []float32 {
+	neff := make([]float32, p.ncol)
+	gappos := int(p.mapping[GapChar])
+	for irow, row := range p.weights.Mat {
+		if irow == gappos {
+			continue
+		}
+		for icol, w := range row {
+			neff[icol] += w
+		}
+	}
+	return neff
+}
+
+// Entropy calculates the entropy at each site into entropy, which the
+// caller allocates.
+func (p *Profile) Entropy(entropy []float32) {
+	EntropyFromArray(p.gapsAreChar, p.freqs.Mat, entropy, p.logbase, p.mapping[GapChar])
+}
+
+// Compat takes one sequence (a reference). At each site, it returns
+// the fraction of the other sequences which have the same residue as
+// refseq. It works from the weighted counts, so it does not depend on
+// the gap policy. Sites where refseq has a gap, or where no other
+// sequence has a residue, are zero.
+func (p *Profile) Compat(refseq []byte) []float32 { return p.CompatWt(refseq, 1) }
+
+// CompatWt is Compat where the reference had weight refWt in the
+// counts, as with SetSeqWeights. That much is taken away to leave
+// the other sequences.
+func (p *Profile) CompatWt(refseq []byte, refWt float32) []float32 {
+	compat := make([]float32, len(refseq))
+	gappos := int(p.mapping[GapChar])
+	for icol, c := range refseq {
+		c = p.remap[c]
+		if c == GapChar {
+			continue
+		}
+		var nres float32
+		for irow := range p.weights.Mat {
+			if irow != gappos {
+				nres += p.weights.Mat[irow][icol]
+			}
+		}
+		if nres-refWt < 0.001 { // It means, we have a lonely insertion
+			continue
+		}
+		nthischar := p.Weight(c, icol) - refWt
+		compat[icol] = nthischar / (nres - refWt)
+		if k := p.priorIndex(c); k != -1 {
+			compat[icol] = p.priorCompat(icol, k, refWt, nres-refWt)
+		}
+	}
+	return compat
+}
+
+// priorIndex is where remapped symbol c is in the prior's symbols, or
+// -1 if there is no prior or it does not know c.
+func (p *Profile) priorIndex(c byte) int {
+	if p.prior == nil || c >= MaxSym || p.mapping[c] == badMap {
+		return -1
+	}
+	for i, irow := range p.priorRow {
+		if irow == int(p.mapping[c]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// priorCompat is the estimated frequency of prior symbol k at icol,
+// after taking refWt of k away. nres is what is left of all residues.
+func (p *Profile) priorCompat(icol, k int, refWt, nres float32) float32 {
+	n, est := make([]float64, len(p.priorRow)), make([]float64, len(p.priorRow))
+	nprior := p.priorCounts(icol, n) - float64(refWt)
+	n[k] = max(n[k]-float64(refWt), 0)
+	p.prior.Estimate(n, est)
+	return float32(est[k] * nprior / float64(nres))
+}
@@ -0,0 +1,125 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// newProfile is NewProfile, but a failure ends the test.
+func newProfile(t *testing.T, seqgrp *SeqGrp, gapsAreChar bool) *Profile {
+	p, err := NewProfile(seqgrp, gapsAreChar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// compat is SeqGrp.Compat, but a failure ends the test.
+func compat(t *testing.T, seqgrp *SeqGrp, refseq []byte, gapsAreChar bool) []float32 {
+	c, err := seqgrp.Compat(refseq, gapsAreChar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// gapFrac is SeqGrp.GapFrac, but a failure ends the test.
+func gapFrac(t *testing.T, seqgrp *SeqGrp) []float32 {
+	g, err := seqgrp.GapFrac()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// TestProfileGapPolicy builds profiles with and without gaps from one
+// SeqGrp. Each must give the same entropy as a fresh SeqGrp.
+func TestProfileGapPolicy(t *testing.T) {
+	ss := []string{"AC-", "AG-", "-GT", "AGT"}
+	seqgrp := Str2SeqGrp(ss)
+	withGaps := newProfile(t, seqgrp, true)
+	noGaps := newProfile(t, seqgrp, false)
+	for _, p := range []*Profile{withGaps, noGaps} {
+		got := make([]float32, p.Len())
+		p.Entropy(got)
+		want := make([]float32, p.Len())
+		Str2SeqGrp(ss).Entropy(p.GapsAreChar(), want)
+		if !sliceEql(got, want) {
+			t.Fatalf("gapsAreChar %v got %v want %v", p.GapsAreChar(), got, want)
+		}
+	}
+	if withGaps.Freq('A', 0) != 0.75 || noGaps.Freq('A', 0) != 1 {
+		t.Fatal("frequencies of A in column 0 wrong")
+	}
+	if g := noGaps.GapFrac(); g[0] != 0.25 || g[2] != 0.5 {
+		t.Fatal("gap fractions wrong", g)
+	}
+}
+
+// TestProfileImmutable changes the SeqGrp and the slices we get back
+// and checks the profile does not see it.
+func TestProfileImmutable(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"Aa-", "AC-", "AC-"})
+	p := newProfile(t, seqgrp, false)
+	if p.Count('A', 0) != 3 || p.Count('a', 1) != 1 || p.Count('W', 1) != 0 {
+		t.Fatal("counts wrong")
+	}
+	g := p.GapFrac()
+	g[2] = 0
+	seqgrp.SeqSlc()[0].GetSeq()[0] = 'C'
+	seqgrp.SetAlphabet(ProteinAlphabet())
+	seqgrp.SetTermGapWeight(0)
+	if p.GapFrac()[2] != 1 || p.Count('A', 0) != 3 || p.Count('a', 1) != 1 {
+		t.Fatal("profile changed with the seqgrp")
+	}
+	if q := newProfile(t, seqgrp, false); q.Count('a', 1) != 1 || q.Count('a', 0) != 2 {
+		t.Fatal("a new profile should see the alphabet")
+	}
+}
+
+// TestProfileKept checks that Entropy, GapFrac and Compat share one
+// Profile, and that changing the SeqGrp throws it away.
+func TestProfileKept(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AC-", "ag-", "AGT"})
+	entropy := make([]float32, 3)
+	seqgrp.Entropy(false, entropy)
+	kept := func(gapsAreChar bool) *Profile {
+		p, err := seqgrp.Profile(gapsAreChar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	p := kept(false)
+	gapFrac(t, seqgrp)
+	compat(t, seqgrp, seqgrp.SeqSlc()[0].GetSeq(), false)
+	if kept(false) != p {
+		t.Fatal("profile was built again")
+	}
+	if kept(true) == p {
+		t.Fatal("gapsAreChar true and false should not share a profile")
+	}
+	seqgrp.Upper()
+	if kept(false) == p {
+		t.Fatal("Upper should throw the profile away")
+	}
+	seqgrp.Entropy(false, entropy)
+	if entropy[0] != 0 {
+		t.Fatal("after Upper, column of A should have no entropy, got", entropy[0])
+	}
+	for _, change := range []func(){
+		func() { seqgrp.SetPrior(NewUniformPrior(DNAAlphabet(), 1)) },
+		func() { seqgrp.SetSeqWeights([]float32{1, 2, 1}) },
+		func() { seqgrp.SetNSym(5) },
+		func() { seqgrp.SetTermGapWeight(0) },
+	} {
+		p = kept(false)
+		change()
+		if kept(false) == p {
+			t.Fatal("profile should have been thrown away")
+		}
+	}
+}
@@ -16,6 +16,12 @@
 //     l.seq is a re-used buffer. After each sequence is complete, we copy
 //     the region we want into l.seqblock and set the used part of the buffer
 //     to length zero.
+//
+// In cases 2 and 3, we may not be able to count the sequences, for
+// example if we are reading from a pipe. Then we allocate a block for
+// some sequences and when it is full, we allocate another one twice as
+// big. Sequences already read stay where they are and are not copied.
+// The same happens if the count was wrong.
 
 package seq
 
@@ -44,10 +50,11 @@ type item struct {
 }
 
 type lexer struct {
+	sink       SeqFunc // If set, sequences go here, not into seqgrp
 	input      []byte
 	ichan      chan *item
 	seqgrp     *SeqGrp
-	rdr        io.ReadSeeker
+	rdr        io.Reader
 	itempool   sync.Pool
 	cmmt       string // partial comment
 	seq        []byte // partial string
@@ -59,14 +66,26 @@ type lexer struct {
 	term       byte   // terminator of comments or sequences
 	memtype    byte   // diff length sequences, same or a range from each seq
 	RmvGapsRd  bool   // copied from s_opts
+	gapChars   string // from s_opts, extra gap characters
 	ZeroLenOK  bool   // from s_opts, zero length seqs OK
 	notfirst   bool   // Not the first call
+	nseq       int    // Number of sequences, if counted before reading
+	sz         int    // Space for one sequence in seqblock
+	line       int    // Line number we have got to, for errors
+	hdrLine    int    // Line where the current record started
+	nrec       int    // Number of records finished
+	leadLines  int    // Blank lines before the first ">", set by next
+	noCmmt     bool   // Input did not start with ">", set by next
 }
 
 const defaultReadSize = 4 * 1024
 
 var rdsize int = defaultReadSize
 
+// streamNSeq is the number of sequences we make space for if we cannot
+// count them beforehand.
+const streamNSeq = 1024
+
 // setFastaRdSize is only used during benchmarking to see the effect of
 // buffer size.
 func setFastaRdSize(i int) {
@@ -87,7 +106,8 @@ func newItem() interface{} { return new(item) }
 
 // next reads from the input and sends an item to channel, ichan.
 // An item is terminated by l.term, or the end of the buffer or
-// end of input.
+// end of input. A pipe or decompressor may give less than we ask for,
+// so we keep reading until the buffer is full or the input ends.
 // Use a pair of buffers for reading. When one is being filled, the other might
 // be processed by the comment or sequence reading function.
 func (l *lexer) next() {
@@ -105,10 +125,10 @@ func (l *lexer) next() {
 				curbuf = &backbuf1
 			}
 			l.input = (*curbuf)[:]
-			if n, err := l.rdr.Read(l.input); n != rdsize { // EOF or error?
+			if n, err := io.ReadFull(l.rdr, l.input); n != rdsize { // EOF or error?
 				l.input = l.input[:n]
 				if n == 0 { // really finished
-					if err != nil && err != io.EOF {
+					if err != io.EOF {
 						l.err = err // Real error (not EOF) occurred.
 					}
 					item.data = nil
@@ -123,9 +143,15 @@ func (l *lexer) next() {
 				}
 			}
 		}
-		if first { // skip over leading ">"
+		if first { // skip over white space and the leading ">"
 			first = false
-			l.input = l.input[1:]
+			trimmed := bytes.TrimLeft(l.input, " \t\r\n")
+			l.leadLines = bytes.Count(l.input[:len(l.input)-len(trimmed)], []byte{NL})
+			if l.input = trimmed; len(l.input) > 0 && l.input[0] == cmmtChar {
+				l.input = l.input[1:]
+			} else if len(l.input) > 0 {
+				l.noCmmt = true
+			}
 		}
 
 		if ndx := bytes.IndexByte(l.input, l.term); ndx == -1 {
@@ -147,43 +173,100 @@ func (l *lexer) next() {
 }
 
 // firstCall is called when we have read up the first sequence and can
-// allocate all the space we need.
+// allocate all the space we need. If the input cannot seek, we cannot
+// count sequences, so we just make space for a guessed number.
 func firstCall(l *lexer) error {
-	const invalidRange = "invalid seq range %d to %d, length is only %d"
-	nseq, err := numseq.ByReading(l.rdr)
-	if err != nil {
-		return err
+	nseq := l.nseq
+	if nseq == 0 {
+		if rs, ok := l.rdr.(io.ReadSeeker); ok {
+			if _, err := rs.Seek(0, io.SeekCurrent); err == nil {
+				if nseq, err = numseq.ByReading(rs); err != nil {
+					return err
+				}
+			}
+		}
 	}
 	if nseq < 1 {
-		return errors.New("no sequences found")
+		nseq = streamNSeq
 	}
-	l.expLen = len(l.seq)
-	var sz int
-	if l.rangeEnd >= l.expLen {
-		return fmt.Errorf(invalidRange, l.rangeStart, l.rangeEnd, l.expLen)
+	if err := setExpLen(l); err != nil {
+		return err
 	}
 	if l.rangeStart != 0 || l.rangeEnd != 0 {
-		sz = l.rangeEnd - l.rangeStart + 1
+		l.sz = l.rangeEnd - l.rangeStart + 1
 	} else {
-		sz = l.expLen
+		l.sz = l.expLen
 	}
-	l.seqblock = make([]byte, 0, sz*nseq)
+	l.nseq = nseq
+	l.seqblock = make([]byte, 0, l.sz*nseq)
 	return nil
 }
 
+// setExpLen takes the expected length from the first sequence and
+// checks the range fits.
+func setExpLen(l *lexer) error {
+	const invalidRange = "invalid seq range %d to %d, length is only %d"
+	l.expLen = len(l.seq)
+	if l.rangeStart < 0 || l.rangeStart > l.rangeEnd || l.rangeEnd >= l.expLen {
+		return fmt.Errorf(invalidRange, l.rangeStart, l.rangeEnd, l.expLen)
+	}
+	return nil
+}
+
+// sendSeq is the end of seqFn when streaming. The sequence goes to
+// l.sink and the buffer is used again for the next one.
+func sendSeq(l *lexer) stateFn {
+	if !l.notfirst && l.memtype != diffLen {
+		l.notfirst = true
+		if l.err = setExpLen(l); l.err != nil {
+			return nil
+		}
+	}
+	if l.memtype != diffLen && l.expLen != len(l.seq) {
+		l.err = &LengthMismatchError{Record: l.nrec, Header: l.cmmt,
+			Line: l.hdrLine, Want: l.expLen, Got: len(l.seq)}
+		return nil
+	}
+	toUse := l.seq
+	if l.memtype == withRange {
+		toUse = l.seq[l.rangeStart : l.rangeEnd+1]
+	}
+	if l.err = l.sink(l.cmmt, toUse); l.err != nil {
+		return nil
+	}
+	l.nrec++
+	l.cmmt = ""
+	l.hdrLine = l.line
+	l.seq = l.seq[:0]
+	return cmmtFn
+}
+
+// makeRoom checks there is space for one more sequence in seqblock.
+// If not, we start a new block, twice as big as the last one.
+func makeRoom(l *lexer) {
+	if cap(l.seqblock)-len(l.seqblock) >= l.sz {
+		return
+	}
+	l.nseq *= 2
+	l.seqblock = make([]byte, 0, l.sz*l.nseq)
+}
+
 type stateFn func(*lexer) stateFn
 
 // seqFn is used to build up a sequence (not comment) and store it when complete.
 // On the first call, we check if the sequences should be of the same length.
 // If so, we allocate a single large block for sequences.
 func seqFn(l *lexer) stateFn {
-	const bustLen = "seqs not same length, wanted %d, got %d"
 	item := <-l.ichan
 	if item == nil || l.err != nil {
 		return nil
 	}
 
+	l.line += bytes.Count(item.data, []byte{NL})
 	white.Remove(&item.data)
+	if l.gapChars != "" {
+		toGapChar(item.data, l.gapChars)
+	}
 	if l.RmvGapsRd {
 		white.CharRemove(&item.data, common.GapChar)
 	}
@@ -193,10 +276,14 @@ func seqFn(l *lexer) stateFn {
 	if complete {
 		if len(l.seq) == 0 {
 			if !l.ZeroLenOK { // zero length seqs usually not OK
-				l.err = errors.New("Zero length sequence after" + l.cmmt)
+				l.err = &ParseError{Record: l.nrec, Header: l.cmmt,
+					Line: l.hdrLine, Err: errZeroLen}
 				return nil
 			}
 		}
+		if l.sink != nil {
+			return sendSeq(l)
+		}
 
 		if !l.notfirst && (l.memtype == sameLen || l.memtype == withRange) {
 			var tmp []byte
@@ -212,7 +299,8 @@ func seqFn(l *lexer) stateFn {
 		}
 		if l.memtype == sameLen || l.memtype == withRange {
 			if l.expLen != len(l.seq) {
-				l.err = fmt.Errorf(bustLen, l.expLen, len(l.seq))
+				l.err = &LengthMismatchError{Record: l.nrec, Header: l.cmmt,
+					Line: l.hdrLine, Want: l.expLen, Got: len(l.seq)}
 				return nil
 			}
 		}
@@ -220,27 +308,30 @@ func seqFn(l *lexer) stateFn {
 		var vseq seq
 		switch l.memtype {
 		case diffLen:
-			vseq = seq{cmmt: l.cmmt, seq: l.seq}
+			vseq = seq{cmmt: l.cmmt, seq: l.seq, line: l.hdrLine}
 		case sameLen:
-			vseq = seq{cmmt: l.cmmt, seq: l.seq}
+			vseq = seq{cmmt: l.cmmt, seq: l.seq, line: l.hdrLine}
 		case withRange:
 			toUse := l.seq[l.rangeStart : l.rangeEnd+1]
+			makeRoom(l)
 			start := len(l.seqblock)
 			l.seqblock = append(l.seqblock, toUse...)
-			vseq = seq{cmmt: l.cmmt, seq: l.seqblock[start : start+len(toUse)]}
+			vseq = seq{cmmt: l.cmmt, seq: l.seqblock[start : start+len(toUse)], line: l.hdrLine}
 		}
 
 		l.seqgrp.seqs = append(l.seqgrp.seqs, vseq)
+		l.nrec++
 		l.cmmt = ""
+		l.hdrLine = l.line
 		switch l.memtype {
 		case diffLen:
 			l.seq = nil // Not using single block for sequences. Forces fresh memory on next.
 		case sameLen:
 			nlen := len(l.seqblock)
 			l.seqblock = l.seqblock[:nlen+len(l.seq)]
+			makeRoom(l)
 			l.seq = l.seqblock[len(l.seqblock):]
 		case withRange:
-			l.seqblock = append(l.seqblock, l.seq[l.rangeStart:l.rangeEnd]...)
 			l.seq = l.seq[:0]
 		}
 		return cmmtFn
@@ -256,9 +347,18 @@ func cmmtFn(l *lexer) stateFn {
 		return nil
 	}
 
+	if l.nrec == 0 && l.cmmt == "" && (l.leadLines > 0 || l.noCmmt) {
+		l.line += l.leadLines // Only happens at the very start
+		l.hdrLine, l.leadLines = l.line, 0
+		if l.noCmmt {
+			l.err = &ParseError{Record: 0, Line: l.line, Err: errNoCmmt}
+			return nil
+		}
+	}
 	l.cmmt = l.cmmt + string(item.data)
 	if item.complete {
 		item.complete = false
+		l.line++
 		return seqFn
 	}
 	return cmmtFn
@@ -288,17 +388,24 @@ func checkBroken(s_opts *Options) error {
 	return nil
 }
 
-// ReadFasta reads fasta formatted files.
-func ReadFasta(rdr io.ReadSeeker, seqgrp *SeqGrp, s_opts *Options) (err error) {
+// ReadFasta reads fasta formatted files. If rdr can seek, we count the
+// sequences first. If not (a pipe or stdin), we read in one pass.
+func ReadFasta(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) (err error) {
+	return readFasta(rdr, seqgrp, s_opts)
+}
+
+// readFasta does the work for ReadFasta.
+func readFasta(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) (err error) {
 	if err := checkBroken(s_opts); err != nil {
 		return err
 	}
 	l := lexer{
 		rdr: rdr, ichan: make(chan *item), seqgrp: seqgrp, term: NL,
-		RmvGapsRd:  s_opts.RmvGapsRd,
+		RmvGapsRd: s_opts.RmvGapsRd, gapChars: s_opts.GapChars,
 		rangeStart: s_opts.RangeStart, rangeEnd: s_opts.RangeEnd,
 		ZeroLenOK: s_opts.ZeroLenOK,
 		memtype:   memtype(s_opts),
+		line:      1, hdrLine: 1, sink: seqgrp.sink,
 	}
 
 	go l.next()
@@ -308,8 +415,9 @@ func ReadFasta(rdr io.ReadSeeker, seqgrp *SeqGrp, s_opts *Options) (err error) {
 	if l.err != nil {
 		return l.err
 	}
-	if seqgrp.NSeq() == 0 {
-		return errors.New("No sequences found")
+	if l.nrec == 0 {
+		return ErrNoSequences
 	}
+	seqgrp.parseHeaders(s_opts.Templates)
 	return nil
 }
@@ -0,0 +1,179 @@
+// 17 Oct 2026
+// Reader for Stockholm format files, as written by HMMER and used by Rfam
+// and Pfam. A file looks like
+//
+//	# STOCKHOLM 1.0
+//	#=GF ID  some_family
+//	#=GS seq1 DE a description
+//	seq1          ACDE.FG
+//	#=GR seq1 SS  HHH.HHE
+//	seq2          ACDEGFG
+//	#=GC SS_cons  HHH.HHE
+//	//
+//
+// The alignment may be broken into interleaved blocks, separated by blank
+// lines. Each block repeats the sequence names and we just append to what
+// we had. Both "." and "-" are gaps in Stockholm files. We turn "." into
+// our gap character in the sequences, but leave annotation lines alone.
+// #=GC lines are kept as per-column annotation and #=GR lines as
+// per-residue annotation for the sequence they name. #=GS DE lines are
+// added to the sequence comment. Other markup (#=GF) is skipped.
+// We stop at the first "//", so only the first alignment in a file is read.
+
+package seq
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+const stockholmMagic = "# STOCKHOLM"
+
+// stockholmGap is the gap character used in Stockholm files, on top of
+// the usual "-".
+const stockholmGap = '.'
+
+// stkSeq gathers the pieces of one sequence from a Stockholm file.
+// line is where the name first turned up and grLine is the first #=GR
+// line for it, so we can say where an orphan annotation came from.
+type stkSeq struct {
+	name   string
+	desc   string
+	seq    []byte
+	annot  map[string][]byte
+	record int
+	line   int
+	grLine int
+}
+
+// newScanner returns a line scanner which will not choke on long lines.
+func newScanner(rdr io.Reader) *bufio.Scanner {
+	const maxLine = 64 * 1024 * 1024
+	scanner := bufio.NewScanner(rdr)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	return scanner
+}
+
+// ReadStockholm reads the first alignment from a Stockholm format file
+// into seqgrp. The options work as for ReadFasta.
+func ReadStockholm(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const badLine = "stockholm: expected name and sequence, got \"%s\""
+	const badMarkup = "stockholm: incomplete markup \"%s\""
+	var order []*stkSeq
+	byName := make(map[string]*stkSeq)
+	colAnnot := make(map[string][]byte)
+	var nline int
+	get := func(name string) *stkSeq {
+		ss, ok := byName[name]
+		if !ok {
+			ss = &stkSeq{name: name, record: len(order), line: nline}
+			byName[name] = ss
+			order = append(order, ss)
+		}
+		return ss
+	}
+	record := func(name string) int { // where a broken line belongs
+		if ss, ok := byName[name]; ok {
+			return ss.record
+		}
+		return len(order)
+	}
+
+	scanner := newScanner(rdr)
+	for nline = 1; scanner.Scan(); nline++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "//":
+			return stkFinish(order, colAnnot, seqgrp, s_opts)
+		case strings.HasPrefix(line, "#=GC"):
+			f := strings.Fields(line)
+			if len(f) < 3 {
+				return &ParseError{Record: -1, Line: nline, Err: fmt.Errorf(badMarkup, line)}
+			}
+			colAnnot[f[1]] = append(colAnnot[f[1]], strings.Join(f[2:], "")...)
+		case strings.HasPrefix(line, "#=GR"):
+			f := strings.Fields(line)
+			if len(f) < 4 {
+				pe := &ParseError{Record: -1, Line: nline, Err: fmt.Errorf(badMarkup, line)}
+				if len(f) > 1 {
+					pe.Record, pe.Header = record(f[1]), f[1]
+				}
+				return pe
+			}
+			ss := get(f[1])
+			if ss.grLine == 0 {
+				ss.grLine = nline
+			}
+			if ss.annot == nil {
+				ss.annot = make(map[string][]byte)
+			}
+			ss.annot[f[2]] = append(ss.annot[f[2]], strings.Join(f[3:], "")...)
+		case strings.HasPrefix(line, "#=GS"):
+			f := strings.Fields(line)
+			if len(f) >= 4 && f[2] == "DE" {
+				ss := get(f[1])
+				ss.desc = strings.TrimSpace(ss.desc + " " + strings.Join(f[3:], " "))
+			}
+		case line[0] == '#': // #=GF and the header
+			continue
+		default:
+			f := strings.Fields(line)
+			if len(f) < 2 {
+				return &ParseError{Record: record(f[0]), Header: f[0], Line: nline,
+					Err: fmt.Errorf(badLine, line)}
+			}
+			ss := get(f[0])
+			for _, piece := range f[1:] {
+				ss.seq = append(ss.seq, piece...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return stkFinish(order, colAnnot, seqgrp, s_opts) // no "//" at the end
+}
+
+// stkFinish moves the collected sequences and annotation into the seqgrp.
+func stkFinish(order []*stkSeq, colAnnot map[string][]byte,
+	seqgrp *SeqGrp, s_opts *Options) error {
+	const badAnnot = "stockholm #=GC %s has length %d, alignment length %d"
+	const orphan = "stockholm: #=GR line for %s, which has no sequence"
+	for _, ss := range order {
+		if len(ss.seq) == 0 && ss.grLine > 0 {
+			return &ParseError{Record: ss.record, Header: ss.name, Line: ss.grLine,
+				Err: fmt.Errorf(orphan, ss.name)}
+		}
+		cmmt := ss.name
+		if ss.desc != "" {
+			cmmt += " " + ss.desc
+		}
+		s := bytes.ReplaceAll(ss.seq, []byte{stockholmGap}, []byte{common.GapChar})
+		seqgrp.seqs = append(seqgrp.seqs, seq{cmmt: cmmt, seq: s, line: ss.line})
+		seqgrp.seqAnnot = append(seqgrp.seqAnnot, ss.annot)
+	}
+	if len(colAnnot) > 0 {
+		seqgrp.colAnnot = colAnnot
+	}
+	if len(order) > 0 && !s_opts.DiffLenSeq {
+		for tag, annot := range colAnnot {
+			if l := len(order[0].seq); len(annot) != l {
+				return fmt.Errorf(badAnnot, tag, len(annot), l)
+			}
+		}
+	}
+	return finishGrp(seqgrp, s_opts)
+}
+
+// isStockholm looks at the start of a file and says if it is a
+// Stockholm file.
+func isStockholm(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n"), []byte(stockholmMagic))
+}
@@ -14,19 +14,25 @@
 package seq
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/andrew-torda/matrix"
-	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
+	"github.com/andrew-torda/seq_compat/pkg/unzip"
+	"github.com/edsrzf/mmap-go"
 )
 
 // seq is the exported type.
 type seq struct {
 	cmmt string
 	seq  []byte
+	qual []byte // Phred scores, if read from fastq, otherwise nil
+	hdr  Header // fields picked out of cmmt when it was read
+	line int    // line where the record starts, zero if not known
 }
 
 // A marker to say what type of sequence we have, protein, DNA, ...
@@ -49,14 +55,24 @@ const (
 
 // Options contains all the choices passed in from the caller.
 type Options struct {
-	RangeStart int  // When reading, keep only range from Start to End.
-	RangeEnd   int  // If Start and End are zero, keep everything.
-	DiffLenSeq bool // false, unless different-length sequences OK
-	ZeroLenOk  bool // Zero length sequences should be kept
-	DryRun     bool // Do not write any files
-	RmvGapsRd  bool // Remove gaps on reading. Usually not.
-	RmvGapsWrt bool // Remove gaps on output
-	ZeroLenOK  bool // Zero-length sequences are allowed
+	RangeStart  int    // When reading, keep only range from Start to End.
+	RangeEnd    int    // If Start and End are zero, keep everything.
+	DiffLenSeq  bool   // false, unless different-length sequences OK
+	ZeroLenOk   bool   // Zero length sequences should be kept
+	DryRun      bool   // Do not write any files
+	RmvGapsRd   bool   // Remove gaps on reading. Usually not.
+	RmvGapsWrt  bool   // Remove gaps on output
+	ZeroLenOK   bool   // Zero-length sequences are allowed
+	A2M         bool   // Input is A2M/A3M, lower case and "." are inserts
+	DropInserts bool   // With A2M, throw away insert columns
+	Interleave  bool   // Write phylip or nexus in interleaved blocks
+	Mmap        bool   // Map the file, sequences point into the mapping
+	NWorker     int    // Parse fasta with this many goroutines if > 1
+	InFormat    string // Read this format, do not guess from the file
+	OutFormat   string // Writefile uses this format, default fasta
+	GapChars    string // Extra gap characters, read as "-", like ".~"
+	// Templates are tried before UniProt and NCBI to pick apart comments
+	Templates []*HeaderTemplate
 }
 
 // Constants
@@ -72,6 +88,20 @@ type SeqGrp struct {
 	seqs      []seq
 	counts    *matrix.FMatrix2d
 	gapcnt    []int32 // count of gaps at each position
+	colAnnot  map[string][]byte   // per-column annotation, like #=GC SS_cons
+	seqAnnot  []map[string][]byte // per-residue annotation, #=GR, same order as seqs
+	matchCol  []bool              // A2M/A3M, true for match state columns
+	mapped    mmap.MMap           // file mapping, if read with Mmap option
+	qualWt    *[256]float32       // weight for each Phred score, or nil
+	seqWt     []float32           // weight of each sequence, or nil
+	prior     Prior               // pseudocounts for NewProfile, or nil
+	profs     [2]*Profile         // from profile(), by gapsAreChar, nil if stale
+	alpha     *Alphabet           // set by SetAlphabet, or nil
+	nSym      int                 // set by SetNSym, zero if not set
+	termWt    *float32            // weight of terminal gaps, or nil
+	cols      *Columns            // column-major copy from Transpose, or nil
+	nWorker   int                 // goroutines for counting, from SetNWorker
+	sink      SeqFunc             // StreamFile, fasta goes here and is not kept
 	stype     SeqType
 	usedKnwn  bool // Do we know how many symbols are used ?
 	freqKnwn  bool // are counts of symbols converted to fractional probabilities ?
@@ -83,11 +113,17 @@ func (s seq) GetSeq() []byte { return s.seq }
 // Function Cmmt returns the comment, including the leading ">"
 func (s seq) Cmmt() string { return s.cmmt }
 
-// Function SetCmmt sets the comment string to something new
+// Function SetCmmt sets the comment string to something new and
+// picks apart the new one for Header, without templates.
 func (s *seq) SetCmmt(newCmmt string) {
 	s.cmmt = newCmmt
+	s.hdr = ParseHeader(newCmmt)
 }
 
+// Qual returns the Phred quality scores, one per base, or nil if the
+// sequence did not come from a fastq file.
+func (s seq) Qual() []byte { return s.qual }
+
 // Function Len
 func (s seq) Len() int { return len(s.seq) }
 
@@ -100,6 +136,7 @@ func (s *seq) SetSeq(t []byte) { s.seq = t }
 func (s *seq) Clear() {
 	s.cmmt = ""
 	s.seq = nil
+	s.hdr = Header{}
 }
 
 // Empty returns true if a sequence has been cleared.
@@ -114,32 +151,22 @@ func (s seq) Empty() bool {
 // Gene_id returns the gene identifier for a sequence.
 // Of course it does not really do that. It just returns the first
 // word in the comment which is likely to be the gene identifier.
+// Header() does a better job of picking apart the comment.
 func (s seq) Gene_id() (gene_id string) {
 	tmp := strings.Fields(s.cmmt)
 	return tmp[0][:]
 }
 
 // Species tries to return the organism from which a sequence
-// comes. Actually, it just looks in the comment line for a string
-// between square brackets and returns it. Given
+// comes. It takes the organism from Header(), so given
 //
 //	> xyz.123 comment here [  homo sapiens]
 //
 // it should return "homo sapiens" with leading and trailing white
-// space removed.
+// space removed. It also finds the OS= field in UniProt headers.
 func (s seq) Species() (species string, ok bool) {
-	var i, j int
-	if i = strings.LastIndexByte(s.cmmt, '['); i == -1 {
-		return
-	}
-	if j = strings.LastIndexByte(s.cmmt, ']'); j == -1 {
-		return
-	}
-	if i >= j { // Is this an error ?
-		return
-	} // We treat it as if there is no comment
-
-	return strings.TrimSpace(s.cmmt[i+1 : j]), true
+	species = s.hdr.Organism
+	return species, species != ""
 }
 
 // Lower will change a sequence to lower case
@@ -192,7 +219,7 @@ func (seq *seq) Upper() error {
 
 // Copy
 func (s *seq) Copy() seq {
-	t := seq{cmmt: s.cmmt}
+	t := seq{cmmt: s.cmmt, hdr: s.hdr, line: s.line}
 	t.SetSeq(s.GetSeq())
 	return t
 }
@@ -214,7 +241,8 @@ func (s seq) String() (t string) {
 // of all sequences.
 func (seqgrp *SeqGrp) GetLen() int { return len(seqgrp.seqs[0].GetSeq()) }
 
-// GetCounts gives us the normally non-exported counts
+// GetCounts gives us the normally non-exported counts. It is nil if
+// UsageSite fails.
 func (seqgrp *SeqGrp) GetCounts() *matrix.FMatrix2d {
 	if seqgrp.counts == nil {
 		seqgrp.UsageSite()
@@ -252,6 +280,63 @@ func (seqgrp *SeqGrp) clear() {
 	seqgrp.stype = Unchecked
 	seqgrp.usedKnwn = false
 	seqgrp.freqKnwn = false
+	seqgrp.stale()
+}
+
+// stale throws away the profiles kept by profile(). Anything which
+// changes what NewProfile would give has to call it, or clear().
+func (seqgrp *SeqGrp) stale() { seqgrp.profs = [2]*Profile{} }
+
+// profile returns the profile for gapsAreChar, building it only if
+// nothing has been kept since the last change.
+func (seqgrp *SeqGrp) profile(gapsAreChar bool) (*Profile, error) {
+	i := 0
+	if gapsAreChar {
+		i = 1
+	}
+	if seqgrp.profs[i] == nil {
+		p, err := NewProfile(seqgrp, gapsAreChar)
+		if err != nil {
+			return nil, err
+		}
+		seqgrp.profs[i] = p
+	}
+	return seqgrp.profs[i], nil
+}
+
+// ColAnnot returns the per-column annotation track with the given tag,
+// such as "SS_cons" or "RF" from a Stockholm file. If there is no such
+// track, it returns nil.
+func (seqgrp *SeqGrp) ColAnnot(tag string) []byte { return seqgrp.colAnnot[tag] }
+
+// ColAnnotTags returns the tags of all per-column annotation tracks, sorted.
+func (seqgrp *SeqGrp) ColAnnotTags() []string { return sortedKeys(seqgrp.colAnnot) }
+
+// SeqAnnot returns the per-residue annotation track with the given tag
+// for sequence number i (numbering from zero), or nil.
+func (seqgrp *SeqGrp) SeqAnnot(i int, tag string) []byte {
+	if i >= len(seqgrp.seqAnnot) {
+		return nil
+	}
+	return seqgrp.seqAnnot[i][tag]
+}
+
+// SeqAnnotTags returns the tags of the annotation tracks for sequence i, sorted.
+func (seqgrp *SeqGrp) SeqAnnotTags(i int) []string {
+	if i >= len(seqgrp.seqAnnot) {
+		return nil
+	}
+	return sortedKeys(seqgrp.seqAnnot[i])
+}
+
+// sortedKeys returns the keys of an annotation map in order.
+func sortedKeys(m map[string][]byte) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // NSeq returns the number of sequences
@@ -261,7 +346,7 @@ func (seqgrp *SeqGrp) NSeq() int { return len(seqgrp.seqs) }
 // Used in testing.
 func (seqgrp *SeqGrp) GetNSym() int {
 	if !seqgrp.usedKnwn {
-		seqgrp.UsageSite()
+		seqgrp.SetSymUsed()
 	}
 	if len(seqgrp.revmap) == 0 {
 		seqgrp.mapsyms()
@@ -278,29 +363,92 @@ func (seqgrp *SeqGrp) SeqSlc() []seq { return seqgrp.seqs }
 // character.
 func (seqgrp *SeqGrp) GetMap(c byte) uint8 { return seqgrp.mapping[c] }
 
-// Upper uppercases all the members of a group of sequences.
-func (seqgrp SeqGrp) Upper() error {
+// Upper uppercases all the members of a group of sequences. Anything
+// counted before is thrown away.
+func (seqgrp *SeqGrp) Upper() error {
+	seqgrp.clear()
 	for _, ss := range seqgrp.seqs {
 		if err := ss.Upper(); err != nil {
+			seqgrp.cols = nil // No longer the same as the rows
 			return err
 		}
 	}
+	if seqgrp.cols != nil { // Keep the column-major copy the same
+		for i, c := range seqgrp.cols.data {
+			if 'a' <= c && c <= 'z' {
+				seqgrp.cols.data[i] -= 'a' - 'A'
+			}
+		}
+	}
 	return nil
 }
 
+// KeepCols keeps the columns where mask is true and removes the rest
+// from every sequence, the annotation and the column-major copy.
+// Anything calculated from the old columns is thrown away. Sequences
+// whose length is not that of mask are left alone.
+func (seqgrp *SeqGrp) KeepCols(mask []bool) {
+	keep := func(b []byte) []byte {
+		if len(b) != len(mask) {
+			return b
+		}
+		t := b[:0]
+		for i, c := range b {
+			if mask[i] {
+				t = append(t, c)
+			}
+		}
+		return t
+	}
+	for i := range seqgrp.seqs {
+		seqgrp.seqs[i].seq = keep(seqgrp.seqs[i].seq)
+		seqgrp.seqs[i].qual = keep(seqgrp.seqs[i].qual)
+	}
+	for tag, annot := range seqgrp.colAnnot {
+		seqgrp.colAnnot[tag] = keep(annot)
+	}
+	for _, m := range seqgrp.seqAnnot {
+		for tag, annot := range m {
+			m[tag] = keep(annot)
+		}
+	}
+	if len(seqgrp.matchCol) == len(mask) {
+		t := seqgrp.matchCol[:0]
+		for i, m := range seqgrp.matchCol {
+			if mask[i] {
+				t = append(t, m)
+			}
+		}
+		seqgrp.matchCol = t
+	}
+	if c := seqgrp.cols; c != nil && c.ncol == len(mask) {
+		n := 0
+		for i := 0; i < c.ncol; i++ {
+			if mask[i] {
+				copy(c.data[n*c.nseq:], c.Col(i))
+				n++
+			}
+		}
+		c.data, c.ncol = c.data[:n*c.nseq], n
+	}
+	seqgrp.clear()
+}
+
 // check_seq_lengths should only be called if we are keeping
 // gaps. Then we imagine all the sequences are aligned, so they
 // must be the same length.
 // For consistency, this should be callable on a seqgrp, not
 // a slice of sequences.
 func check_lengths(seq_set []seq) error {
-	msg := `Sequence lengths are not the same. First sequence length %d, but
-sequence %i length: %i. Sequence starts %s"`
+	if len(seq_set) == 0 {
+		return nil
+	}
 	iwant := len(seq_set[0].GetSeq())
 	for i := 1; i < len(seq_set); i++ {
 		ilen := len(seq_set[i].GetSeq())
 		if ilen != iwant {
-			return (fmt.Errorf(msg, iwant, ilen, trimStr(seq_set[i].Cmmt(), 40)))
+			return &LengthMismatchError{Record: i, Header: seq_set[i].Cmmt(),
+				Line: seq_set[i].line, Want: iwant, Got: ilen}
 		}
 	}
 	return nil
@@ -308,14 +456,31 @@ sequence %i length: %i. Sequence starts %s"`
 
 // Readfile takes a filename and reads sequences from it.
 // each in turn. It returns a SeqGrp and error.
+// An empty filename or "-" means standard input, which may be a pipe.
+// With the Mmap option, a fasta file is mapped into memory and the
+// sequences point into it. Call Close on the SeqGrp when finished.
+// With NWorker more than one, fasta is parsed in parallel.
 func Readfile(fname string, s_opts *Options) (*SeqGrp, error) {
 	var seqgrp = new(SeqGrp)
-	var err error
+	return seqgrp, readfile(fname, seqgrp, s_opts)
+}
+
+// readfile does the work for Readfile and StreamFile.
+func readfile(fname string, seqgrp *SeqGrp, s_opts *Options) error {
 	var rdr io.ReadSeeker // don't use a file. It could be stdin.
 
+	if fname == "-" {
+		fname = ""
+	}
+	if (s_opts.Mmap || s_opts.NWorker > 1) && fname != "" {
+		if err := readMmap(fname, seqgrp, s_opts); err != nil {
+			return fmt.Errorf("Reading from %s: %w", fname, err)
+		}
+		return nil
+	}
 	if fname != "" {
 		if fp, err := os.Open(fname); err != nil {
-			return nil, err
+			return err
 		} else {
 			defer fp.Close()
 			rdr = fp // Only promise a readseeker to the rest of the code
@@ -324,86 +489,130 @@ func Readfile(fname string, s_opts *Options) (*SeqGrp, error) {
 		rdr = os.Stdin
 	}
 
-	if err := ReadFasta(rdr, seqgrp, s_opts); err != nil {
+	if err := readAny(rdr, seqgrp, s_opts); err != nil {
 		if fname == "" {
 			fname = "standard input"
 		}
-		err = fmt.Errorf("Reading from %s: %w", fname, err)
-		return seqgrp, err
+		return fmt.Errorf("Reading from %s: %w", fname, err)
 	}
 
-	if !s_opts.RmvGapsRd {
-		check_lengths(seqgrp.seqs)
+	if !s_opts.RmvGapsRd && !s_opts.DiffLenSeq {
+		if err := check_lengths(seqgrp.seqs); err != nil {
+			return fmt.Errorf("Reading from %s: %w", fname, err)
+		}
 	}
-	return seqgrp, err
+	return nil
 }
 
-// WriteToF takes a filename and a slice of sequences.
-// It writes the sequences to the file.
-// For each sequence, it should check if the sequence has been
-// set to nil.
-// What I could change: If we are removing gaps, we make a buffer which grows
-// character by character via WriteByte(). I could make a buffer beforehand
-// and grow as necessary.
-// This should also really act on a seqgrp.
-func WriteToF(outseq_fname string, seq_set []seq, s_opts *Options) (err error) {
-	const c_per_line = 60
+// readAny looks at the start of the input to decide what format it is in
+// and calls the right reader. Anything we do not recognise is treated as
+// fasta. If we cannot seek back to the start (a pipe), the bytes we looked
+// at are put back in front of the rest of the input.
+// Compressed input is unpacked and we look again at what comes out.
+func readAny(rdr io.Reader, seqgrp *SeqGrp, s_opts *Options) error {
+	const sniffLen = 512
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(rdr, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	head = head[:n]
+	rs, seekable := rdr.(io.ReadSeeker)
+	if seekable {
+		_, err := rs.Seek(0, io.SeekStart)
+		seekable = err == nil
+	}
+	if !seekable {
+		rdr = io.MultiReader(bytes.NewReader(head), rdr)
+	}
+	if kind := unzip.Sniff(head); kind != unzip.None {
+		return readZipped(rdr, kind, seqgrp, s_opts)
+	}
+	format, err := inFormat(head, s_opts)
+	if err != nil {
+		return err
+	}
+	if format.Read == nil {
+		return fmt.Errorf("cannot read %s format", format.Name)
+	}
+	if format.Name != fastaName { // fasta needs more care, below
+		return format.Read(rdr, seqgrp, s_opts)
+	}
+	if s_opts.NWorker > 1 {
+		b, err := io.ReadAll(rdr)
+		if err != nil {
+			return err
+		}
+		_, err = readChunks(b, seqgrp, s_opts)
+		return err
+	}
+	return readFasta(rdr, seqgrp, s_opts)
+}
+
+// readZipped reads gzip or bzip2 compressed input. It is decompressed
+// once. A decompression stream cannot seek, so fasta cannot be counted
+// first and goes into blocks which grow, as from a pipe.
+func readZipped(rdr io.Reader, kind unzip.Kind, seqgrp *SeqGrp, s_opts *Options) error {
+	zr, err := unzip.NewReader(rdr, kind)
+	if err != nil {
+		return err
+	}
+	return readAny(zr, seqgrp, s_opts)
+}
+
+// WriteToF takes a filename and a slice of sequences and writes them
+// in fasta format. A filename of "" means standard output. It is a
+// wrapper around Writer, which is more flexible.
+func WriteToF(outseq_fname string, seq_set []seq, s_opts *Options) error {
 	var nilstring string
-	var outfile_fp io.Writer
 	switch {
 	case s_opts.DryRun:
-		outfile_fp = io.Discard
+		return writeSeqs(io.Discard, seq_set, s_opts)
 	case outseq_fname == nilstring:
-		outfile_fp = os.Stdout
-	default:
-		t, err := os.Create(outseq_fname)
-		if err != nil {
-			return fmt.Errorf("Creating output sequence file: %v", err)
-		}
-		defer t.Close()
-		outfile_fp = t
+		return writeSeqs(os.Stdout, seq_set, s_opts)
 	}
+	fp, err := os.Create(outseq_fname)
+	if err != nil {
+		return fmt.Errorf("Creating output sequence file: %w", err)
+	}
+	if err := writeSeqs(fp, seq_set, s_opts); err != nil {
+		fp.Close()
+		return err
+	}
+	return fp.Close()
+}
 
-	var t []byte
-	for _, seq := range seq_set {
-		if seq.Empty() {
-			continue
-		}
-		fmt.Fprintf(outfile_fp, "%c%s\n", cmmt_char, seq.Cmmt())
-
-		s := seq.GetSeq()
-		if s_opts.RmvGapsWrt { // we have to remove gap characters on output
-			n := 0
-			for i := range s { //    So we start by looking how many non-gap
-				if s[i] != GapChar { //  characters there are.
-					n++
-				}
-			}
-			if cap(t) < n { // See if our scratch space is big enough
-				t = make([]byte, n)
-			}
+// WriteFasta writes the sequences in a seqgrp in fasta format, 60
+// residues per line.
+func WriteFasta(w io.Writer, seqgrp *SeqGrp, s_opts *Options) error {
+	return writeSeqs(w, seqgrp.seqs, s_opts)
+}
 
-			m := 0
-			for i := range s {
-				if s[i] != GapChar {
-					t[m] = s[i]
-					m++
-				}
-			}
-			s = t[:n]
-		}
-		for ; len(s) > c_per_line; s = s[c_per_line:] {
-			fmt.Fprint(outfile_fp, string(s[:c_per_line]), "\n")
+// writeSeqs does the work for WriteToF and WriteFasta.
+func writeSeqs(w io.Writer, seq_set []seq, s_opts *Options) error {
+	wrtr := NewWriter(w)
+	wrtr.RmvGaps = s_opts.RmvGapsWrt
+	for _, s := range seq_set {
+		if err := wrtr.WriteSeq(s); err != nil {
+			return err
 		}
-		fmt.Fprint(outfile_fp, string(s), "\n")
 	}
-	return
+	return wrtr.Flush()
 }
 
 // FindNdx Returns the index of the sequence containing a string.
 // Numbering starts from zero. We remove any ">", space or tab at the start.
+// First, we look for a sequence whose accession or entry name (from
+// Header) is exactly s, so "P69905" or "HBA_HUMAN" finds
+// "sp|P69905|HBA_HUMAN". If there is none, we take the first sequence
+// whose comment contains s.
 func (seqgrp *SeqGrp) FindNdx(s string) int {
 	s = strings.TrimLeft(s, " >	")
+	for i, seq := range seqgrp.seqs {
+		if h := seq.hdr; h.Accession == s || h.EntryName == s {
+			return i
+		}
+	}
 
 	for i, seq := range seqgrp.seqs {
 		if strings.Contains(seq.Cmmt(), s) {
@@ -426,7 +635,8 @@ func Str2SeqGrp(sIn []string, prefix ...string) *SeqGrp {
 		base = prefix[0]
 	}
 	for i, s := range sIn {
-		f := seq{cmmt: fmt.Sprint(base, i), seq: []byte(s)}
+		cmmt := fmt.Sprint(base, i)
+		f := seq{cmmt: cmmt, seq: []byte(s), hdr: ParseHeader(cmmt)}
 		seqgrp.seqs = append(seqgrp.seqs, f)
 	}
 	return seqgrp
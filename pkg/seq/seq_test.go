@@ -573,9 +573,9 @@ func TestCompat(t *testing.T) {
 
 		slc := seqgrp.SeqSlc()
 		sq := slc[n].GetSeq()
-		compat := seqgrp.Compat(sq, false)
-		if !sliceEql(compat, exp.v) {
-			t.Fatal("Set", i, "expected", exp.v, "got", compat)
+		c := compat(t, seqgrp, sq, false)
+		if !sliceEql(c, exp.v) {
+			t.Fatal("Set", i, "expected", exp.v, "got", c)
 		}
 	}
 }
@@ -729,3 +729,19 @@ func TestSeqInfo(t *testing.T) {
 		t.Fatal("Did not change sequence bbbbb properly")
 	}
 }
+
+// TestKeepCols drops the all-gap columns from the sequences.
+func TestKeepCols(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"A-c-", "G-t-", "a-T-"})
+	seqgrp.KeepCols([]bool{true, false, true, false})
+	seqgrp.Upper()
+	want := []string{"AC", "GT", "AT"}
+	for i, ss := range seqgrp.SeqSlc() {
+		if string(ss.GetSeq()) != want[i] {
+			t.Fatalf("seq %d got %s want %s", i, ss.GetSeq(), want[i])
+		}
+	}
+	if p := newProfile(t, seqgrp, false); p.Count('T', 1) != 2 {
+		t.Fatal("counts after KeepCols wrong")
+	}
+}
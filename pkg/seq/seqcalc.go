@@ -6,7 +6,10 @@
 package seq
 
 import (
+	"bytes"
+	"fmt"
 	"math"
+	"strings"
 
 	"github.com/andrew-torda/matrix"
 	. "github.com/andrew-torda/seq_compat/pkg/seq/common"
@@ -27,10 +30,11 @@ type SymSync struct {
 // marked used in group B and vice versa. If we get a second varadic
 // argument, it is a channel to be used in combining.
 func (seqgrp *SeqGrp) SetSymUsed() {
+	remap := seqgrp.remapTable()
 	for _, ss := range seqgrp.seqs {
 		s := ss.GetSeq()
 		for _, c := range s {
-			seqgrp.symUsed[c] = true
+			seqgrp.symUsed[remap[c]] = true
 		}
 	}
 	seqgrp.usedKnwn = true
@@ -45,23 +49,24 @@ func (seqgrp *SeqGrp) SetSymUsedWithChan(frmMrgChn, toMrgChn chan [MaxSym]bool)
 }
 
 // GetType looks at a set of sequences and returns its best guess
-// as to the type of file.
+// as to the type of file. If there is an alphabet, it is the
+// alphabet's type.
 func (seqgrp *SeqGrp) GetType() SeqType {
 	if seqgrp.stype != Unchecked { // If the sequence type has been
 		return seqgrp.stype //        set, just return it.
 	}
+	if seqgrp.alpha != nil {
+		return seqgrp.alpha.stype
+	}
 
 	if seqgrp.usedKnwn != true {
 		seqgrp.SetSymUsed()
 	}
-	protType := []byte{
-		'D', 'E', 'F', 'H', 'I', 'K', 'L', 'M',
-		'N', 'P', 'Q', 'R', 'S', 'V', 'W', 'Y'}
 
 	used := seqgrp.symUsed
-	for _, c := range protType { // If we see an amino acid code,
-		if used[c] { //          just return protein type.
-			return Protein
+	for _, c := range []byte(protSymbols) { // If we see an amino acid code
+		if !strings.ContainsRune(dnaSymbols, rune(c)) && used[c] { // which
+			return Protein //                   is not a base, it is protein.
 		}
 	}
 
@@ -115,19 +120,197 @@ func (seqgrp *SeqGrp) mapsyms() error {
 // and converted to a fraction.
 // Inaccuracy introduced by working with floats is no problem and we
 // can avoid allocating a new matrix for the frequencies.
-func (seqgrp *SeqGrp) UsageSite() {
+// If SetQualWeight has been called, bases from fastq files are weighted
+// by their quality. If SetTermGapWeight has been called, terminal gaps
+// are weighted. If SetSeqWeights has been called, each sequence counts
+// its weight. After Transpose, it counts from the column-major copy. If the weights no longer fit the sequences, the counts
+// are left as they were and the error is returned.
+func (seqgrp *SeqGrp) UsageSite() error {
 	if len(seqgrp.revmap) == 0 {
 		seqgrp.mapsyms()
 	}
 	nrow := len(seqgrp.revmap)
 	ncol := len(seqgrp.seqs[0].GetSeq())
-	seqgrp.counts = matrix.NewFMatrix2d(nrow, ncol)
+	counts := matrix.NewFMatrix2d(nrow, ncol)
+	if err := seqgrp.tally(nil, counts.Mat); err != nil {
+		return err
+	}
+	seqgrp.counts = counts
+	return nil
+}
+
+// tally does the counting for UsageSite and NewProfile. wt gets the
+// counts, weighted if there are weights. If raw is not nil, it gets the
+// plain, unweighted counts.
+// Counting is done in whole numbers, with terminal gaps counted apart,
+// and only then converted and weighted. This is what lets countShards
+// and tallyCols split the work and still give exactly the same floats.
+// After Transpose, the symbols are counted from the columns and only
+// the terminal gaps from the rows.
+// Quality and sequence weights are different for every base or
+// sequence, so they are summed in order by tallyQual, in one goroutine.
+func (seqgrp *SeqGrp) tally(raw [][]int32, wt [][]float32) error {
+	if seqgrp.qualWt != nil || seqgrp.seqWt != nil {
+		return seqgrp.tallyQual(raw, wt)
+	}
+	if raw == nil {
+		raw = matrix.NewIMatrix2d(len(wt), seqgrp.GetLen()).Mat
+	}
+	var term []int32 // terminal gaps in each column
+	if c := seqgrp.cols; c != nil && c.nseq == len(seqgrp.seqs) && c.ncol == seqgrp.GetLen() {
+		seqgrp.tallyCols(raw)
+		if seqgrp.termWt != nil {
+			term = seqgrp.termGapCount()
+		}
+	} else {
+		if seqgrp.termWt != nil {
+			term = make([]int32, seqgrp.GetLen())
+		}
+		seqgrp.countShards(raw, term)
+	}
+	weigh(raw, term, seqgrp.mapping[GapChar], seqgrp.termWt, wt)
+	return nil
+}
+
+// weigh converts whole number counts to floats in wt. If term is not
+// nil, the terminal gaps in it only count termWt.
+func weigh(raw [][]int32, term []int32, gappos uint8, termWt *float32, wt [][]float32) {
+	for irow := range raw {
+		for icol, n := range raw[irow] {
+			wt[irow][icol] = float32(n)
+		}
+	}
+	if term != nil && gappos != badMap {
+		w := *termWt
+		for icol, n := range term {
+			wt[gappos][icol] = float32(raw[gappos][icol]-n) + w*float32(n)
+		}
+	}
+}
+
+// countSeqs counts the symbols in seqs into raw. If term is not nil, it
+// also counts the terminal gaps in each column.
+func (seqgrp *SeqGrp) countSeqs(seqs []seq, raw [][]int32, term []int32) {
+	remap := seqgrp.remapTable()
+	for _, ss := range seqs {
+		for i, c := range ss.seq {
+			raw[seqgrp.mapping[remap[c]]][i]++
+		}
+		if term == nil {
+			continue
+		}
+		first, last := termEnds(ss.seq, remap)
+		for i := 0; i < first; i++ {
+			term[i]++
+		}
+		for i := last + 1; i < len(ss.seq); i++ {
+			term[i]++
+		}
+	}
+}
+
+// tallyQual is tally with quality or sequence weights. Terminal gap
+// weights are applied here too. It is an error if the number of
+// sequences changed after SetSeqWeights.
+func (seqgrp *SeqGrp) tallyQual(raw [][]int32, wt [][]float32) error {
+	const changed = "%d sequence weights for %d sequences, sequences changed after SetSeqWeights"
+	remap := seqgrp.remapTable()
+	if seqgrp.seqWt != nil && len(seqgrp.seqWt) != len(seqgrp.seqs) {
+		return fmt.Errorf(changed, len(seqgrp.seqWt), len(seqgrp.seqs))
+	}
+	for is, ss := range seqgrp.seqs {
+		sw := float32(1)
+		if seqgrp.seqWt != nil {
+			sw = seqgrp.seqWt[is]
+		}
+		weightedCount(seqgrp, &ss, sw, wt)
+		if raw == nil {
+			continue
+		}
+		for i, c := range ss.seq {
+			raw[seqgrp.mapping[remap[c]]][i]++
+		}
+	}
+	return nil
+}
+
+// weightedCount is tallyQual for one sequence, whose weight is sw.
+func weightedCount(seqgrp *SeqGrp, ss *seq, sw float32, wt [][]float32) {
+	remap := seqgrp.remapTable()
+	first, last := 0, len(ss.seq)-1
+	if seqgrp.termWt != nil {
+		first, last = termEnds(ss.seq, remap)
+	}
+	for i, c := range ss.seq {
+		c = remap[c]
+		w := float32(1)
+		switch {
+		case c == GapChar:
+			if i < first || i > last {
+				w = *seqgrp.termWt
+			}
+		case seqgrp.qualWt != nil && ss.qual != nil:
+			w = seqgrp.qualWt[ss.qual[i]]
+		}
+		wt[seqgrp.mapping[c]][i] += sw * w
+	}
+}
+
+// termEnds returns the first and last places in s which are not gaps.
+// Gaps before first or after last are terminal gaps, usually from a
+// fragment. If s is all gaps, first is len(s) and last is before it.
+func termEnds(s []byte, remap *[256]uint8) (first, last int) {
+	for first < len(s) && remap[s[first]] == GapChar {
+		first++
+	}
+	last = len(s) - 1
+	for last >= first && remap[s[last]] == GapChar {
+		last--
+	}
+	return first, last
+}
+
+// SetTermGapWeight says how much leading and trailing gaps count in
+// UsageSite, compared to internal gaps. With 1, they are the same as
+// any other gap. With 0, they are ignored, so a column where most
+// sequences are fragments is judged by the sequences which reach it.
+// GapFrac and UsageFrac see the weighted counts. Any counts already
+// calculated are thrown away.
+func (seqgrp *SeqGrp) SetTermGapWeight(w float32) {
+	seqgrp.clear()
+	seqgrp.termWt = nil
+	if w != 1 {
+		seqgrp.termWt = &w
+	}
+}
+
+// termGapCount returns the number of sequences with a terminal gap at
+// each position.
+func (seqgrp *SeqGrp) termGapCount() []int32 {
+	count := make([]int32, seqgrp.GetLen())
+	remap := seqgrp.remapTable()
 	for _, ss := range seqgrp.seqs {
-		for i, c := range ss.GetSeq() {
-			cmap := seqgrp.mapping[c]
-			seqgrp.counts.Mat[cmap][i] += 1
+		first, last := termEnds(ss.seq, remap)
+		for i := range ss.seq {
+			if i < first || i > last {
+				count[i]++
+			}
 		}
 	}
+	return count
+}
+
+// TermGapFrac returns the fraction of sequences with a leading or
+// trailing gap at each position. It does not depend on the weight from
+// SetTermGapWeight.
+func (seqgrp *SeqGrp) TermGapFrac() []float32 {
+	count := seqgrp.termGapCount()
+	frac := make([]float32, len(count))
+	nseq := float32(seqgrp.NSeq())
+	for i, n := range count {
+		frac[i] = float32(n) / nseq
+	}
+	return frac
 }
 
 // Usage Frac converts count to normalised frequencies. If letter 'A'
@@ -143,21 +326,35 @@ func (seqgrp *SeqGrp) UsageSite() {
 // This means that the fractions of non-gaps adds up to 1,
 // and then you have a bit more due to gaps.
 // It also means that the data looks correct when you plot it out.
-func (seqgrp *SeqGrp) UsageFrac(gapsAreChar bool) {
+// The counts are overwritten, so after this, they are frequencies for
+// this gap policy. NewProfile does not have this problem.
+func (seqgrp *SeqGrp) UsageFrac(gapsAreChar bool) error {
 	if seqgrp.counts == nil {
-		seqgrp.UsageSite()
+		if err := seqgrp.UsageSite(); err != nil {
+			return err
+		}
 	}
-	counts := seqgrp.counts
-	gappos := seqgrp.mapping[GapChar]
+	normalise(seqgrp.counts.Mat, seqgrp.mapping[GapChar], gapsAreChar)
+	seqgrp.freqKnwn = true
+	return nil
+}
+
+// normalise turns counts into frequencies in place, as described for
+// UsageFrac. gappos is the row with gaps, or badMap if there is none.
+func normalise(counts [][]float32, gappos uint8, gapsAreChar bool) {
 	thereAreGaps := true
 	if gappos == badMap {
 		thereAreGaps = false
 	}
-	nrow, ncol := counts.Size()
+	nrow := len(counts)
+	if nrow == 0 {
+		return
+	}
+	ncol := len(counts[0])
 	total := make([]float32, ncol) // total observations in each column
 	for icol := 0; icol < ncol; icol++ {
 		for irow := 0; irow < nrow; irow++ {
-			total[icol] += counts.Mat[irow][icol]
+			total[icol] += counts[irow][icol]
 		}
 	}
 	var savedGapFrac []float32
@@ -165,72 +362,120 @@ func (seqgrp *SeqGrp) UsageFrac(gapsAreChar bool) {
 		if gapsAreChar == false {
 			savedGapFrac = make([]float32, ncol)
 			for icol := range savedGapFrac {
-				savedGapFrac[icol] = counts.Mat[gappos][icol] / total[icol]
+				savedGapFrac[icol] = 1 // Nothing counted, as good as all gaps
+				if total[icol] != 0 {
+					savedGapFrac[icol] = counts[gappos][icol] / total[icol]
+				}
 			}
 			for icol := 0; icol < ncol; icol++ { // Remove gaps from the totals
-				total[icol] -= counts.Mat[gappos][icol]
+				total[icol] -= counts[gappos][icol]
 			}
 		}
 	}
 	for icol := 0; icol < ncol; icol++ { // Normalise the counts
 		for irow := 0; irow < nrow; irow++ {
 			if total[icol] != 0 {
-				counts.Mat[irow][icol] /= (total[icol])
+				counts[irow][icol] /= (total[icol])
 			}
 		}
 	}
 	// The gaps have to be corrected. They have to be a fraction of the
 	// original column totals
 	for icol := range savedGapFrac {
-		counts.Mat[gappos][icol] = savedGapFrac[icol]
+		counts[gappos][icol] = savedGapFrac[icol]
 	}
-	seqgrp.freqKnwn = true
 }
 
 // GapFrac looks in a SeqGrp and returns a slice with the fraction
 // of gap characters at each position. If there are no gaps, there
 // is no slice so we quietly return nil without signalling an error.
-func (seqgrp *SeqGrp) GapFrac() []float32 {
-	if !seqgrp.freqKnwn {
-		gapsAreChar := true // Does not matter what we say here
-		seqgrp.UsageFrac(gapsAreChar)
+// It uses the Profile kept for Entropy or Compat, so calling all three
+// only counts once. After Transpose, it is counted from the columns.
+func (seqgrp *SeqGrp) GapFrac() ([]float32, error) {
+	for _, p := range seqgrp.profs {
+		if p != nil { // Does not matter which gapsAreChar it was for
+			return p.GapFrac(), nil
+		}
 	}
-	gappos := seqgrp.mapping[GapChar]
-	if gappos == badMap {
+	p, err := seqgrp.profile(true)
+	if err != nil {
+		return nil, err
+	}
+	return p.GapFrac(), nil
+}
+
+// unknownSyms are ambiguity codes, if we do not have an alphabet.
+const unknownSyms = "XN"
+
+// SetNSym says how many symbols the sequences really have, instead of
+// the guess from the alphabet. This is the -n option, for example to
+// say a DNA alignment with some N's has 4 symbols.
+// It must be at least the number of different symbols seen, not
+// counting gaps and ambiguity codes. Upper and lower case count as one
+// symbol. If there is no alphabet, X and N are taken as ambiguity codes.
+// Zero or less means go back to guessing.
+func (seqgrp *SeqGrp) SetNSym(n int) error {
+	const tooFew = "%d symbols is too few, %d different symbols (%s) in the sequences"
+	if n <= 0 {
+		seqgrp.nSym = 0
+		seqgrp.stale()
 		return nil
 	}
-	return seqgrp.counts.Mat[gappos]
+	if !seqgrp.usedKnwn {
+		seqgrp.SetSymUsed()
+	}
+	isAmbig := func(c byte) bool { return strings.IndexByte(unknownSyms, c) != -1 }
+	if seqgrp.alpha != nil {
+		isAmbig = seqgrp.alpha.IsAmbig
+	}
+	var seen []byte
+	for i, used := range seqgrp.symUsed {
+		c := byte(i)
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if used && c != GapChar && !isAmbig(c) && bytes.IndexByte(seen, c) == -1 {
+			seen = append(seen, c)
+		}
+	}
+	if n < 2 || n < len(seen) {
+		return fmt.Errorf(tooFew, n, len(seen), seen)
+	}
+	seqgrp.nSym = n
+	seqgrp.stale()
+	return nil
 }
 
-// GetLogBase returns the base to be used for logarithms
+// GetLogBase returns the base to be used for logarithms. It is the
+// number of symbols given to SetNSym, or the size of the alphabet (20
+// for protein, 4 for nucleotides), plus one if gaps are a character.
+// Ambiguity codes, like X or N, and anything else outside the alphabet
+// are counted in rows of their own, so each one seen adds one to the
+// size of the alphabet. Otherwise, the entropy could be more than one.
+// If we do not know the alphabet, it is the number of different symbols.
 func (seqgrp *SeqGrp) GetLogBase(gapsAreChar bool) (nSym int) {
-	const progbug = "program bug in GetLogBase"
-	if !seqgrp.usedKnwn {
-		seqgrp.UsageSite()
+	if len(seqgrp.revmap) == 0 {
+		seqgrp.mapsyms()
 	}
-	if gapsAreChar {
-		switch seqgrp.GetType() {
-		case DNA, RNA, Ntide:
-			nSym = 5 // 4 nucleotides + gap character
-		case Protein:
-			nSym = 21 // 20 residues, + gap
-		case Unknown:
-			nSym = len(seqgrp.revmap)
-		default:
-			panic(progbug)
+	if seqgrp.nSym > 0 {
+		if gapsAreChar {
+			return seqgrp.nSym + 1
 		}
-	} else { // gaps are ignored
-		switch seqgrp.GetType() {
-		case DNA, RNA, Ntide:
-			nSym = 4
-		case Protein:
-			nSym = 20
-		case Unknown:
-			nSym = len(seqgrp.revmap)
-		default:
-			panic(progbug)
+		return seqgrp.nSym
+	}
+	alpha := seqgrp.Alphabet()
+	if alpha == nil {
+		return len(seqgrp.revmap)
+	}
+	nSym = alpha.Size()
+	for i, used := range seqgrp.symUsed {
+		if c := byte(i); used && c != GapChar && !alpha.IsSymbol(c) {
+			nSym++
 		}
 	}
+	if gapsAreChar {
+		nSym++
+	}
 	return nSym
 }
 
@@ -286,44 +531,30 @@ func EntropyFromArray(gapsAreChar bool,
 // If the sequence is unknown, we use the log base the number different
 // symbols
 // The caller allocates space for the result (entropy).
-func (seqgrp *SeqGrp) Entropy(gapsAreChar bool, entropy []float32) {
-	if !seqgrp.freqKnwn {
-		seqgrp.UsageFrac(gapsAreChar)
+// The Profile is built once and kept for GapFrac, Compat and the next
+// call, until one of the Set functions, Upper or KeepCols changes
+// things. If sequences are changed behind the SeqGrp's back, for
+// example with SetSeq, call NewProfile yourself.
+func (seqgrp *SeqGrp) Entropy(gapsAreChar bool, entropy []float32) error {
+	p, err := seqgrp.profile(gapsAreChar)
+	if err != nil {
+		return err
 	}
-	logbase := seqgrp.GetLogBase(gapsAreChar)
-	gapMapping := seqgrp.GetMapping(GapChar)
-	EntropyFromArray(gapsAreChar, seqgrp.counts.Mat, entropy, logbase, gapMapping)
+	p.Entropy(entropy)
+	return nil
 }
 
 // Compat takes one sequence (a reference). It returns the frequency of each
 // character from this sequence at each position in the alignment.
 // Do you want to remove the reference sequence from the calculations ?
 // Usually yes.
-func (seqgrp *SeqGrp) Compat(refseq []byte, gapsAreChar bool) []float32 {
-	if !seqgrp.freqKnwn { // Make sure symbol frequencies have been calculated
-		seqgrp.UsageFrac(gapsAreChar)
-	}
-	compat := make([]float32, len(seqgrp.seqs[0].GetSeq()))
-	ntotal := seqgrp.NSeq()
-	gapfrac := seqgrp.GapFrac()
-	if gapfrac == nil {
-		gapfrac = make([]float32, len(seqgrp.seqs[0].GetSeq()))
-	}
-
-	for i, c := range refseq {
-		if c == GapChar {
-			compat[i] = 0
-			continue
-		}
-		nseq := (1 - gapfrac[i]) * float32(ntotal)
-		if nseq < 1.001 { // It means, we have a lonely insertion
-			compat[i] = 0
-		} else {
-			ic := seqgrp.GetMap(c)
-			fracC := seqgrp.counts.Mat[ic][i]
-			nthischar := fracC*nseq - 1
-			compat[i] = nthischar / (nseq - 1)
-		}
+// It is Profile.Compat, which does not depend on gapsAreChar. With
+// sequence weights, the reference takes away the weight of the first
+// sequence the same as refseq.
+func (seqgrp *SeqGrp) Compat(refseq []byte, gapsAreChar bool) ([]float32, error) {
+	p, err := seqgrp.profile(gapsAreChar)
+	if err != nil {
+		return nil, err
 	}
-	return compat
+	return p.CompatWt(refseq, seqgrp.weightOf(refseq)), nil
 }
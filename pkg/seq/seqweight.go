@@ -0,0 +1,109 @@
+// 17 Oct 2026
+// Sequence weights. Alignments are often full of near-identical
+// sequences from a few well studied clades. Counted one each, they
+// swamp everything else and make sites look more conserved than they
+// are. With SetSeqWeights, each sequence counts its weight in
+// UsageSite, so also in UsageFrac, NewProfile, Entropy and Compat.
+// HenikoffWeights are the position-based weights of Henikoff and
+// Henikoff (1994) J Mol Biol 243, 574-578.
+
+package seq
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/andrew-torda/matrix"
+)
+
+// SetSeqWeights gives each sequence a weight, in the same order as the
+// sequences, for counting in UsageSite. nil goes back to each sequence
+// counting one. Any counts already calculated are thrown away. If
+// sequences are added or removed, the weights have to be set again.
+func (seqgrp *SeqGrp) SetSeqWeights(w []float32) error {
+	const mismatch = "%d sequence weights for %d sequences"
+	if w != nil && len(w) != len(seqgrp.seqs) {
+		return fmt.Errorf(mismatch, len(w), len(seqgrp.seqs))
+	}
+	seqgrp.counts = nil
+	seqgrp.freqKnwn = false
+	seqgrp.stale()
+	seqgrp.seqWt = nil
+	if w != nil {
+		seqgrp.seqWt = append([]float32(nil), w...)
+	}
+	return nil
+}
+
+// SeqWeights returns a copy of the weights from SetSeqWeights, or nil.
+func (seqgrp *SeqGrp) SeqWeights() []float32 {
+	if seqgrp.seqWt == nil {
+		return nil
+	}
+	return append([]float32(nil), seqgrp.seqWt...)
+}
+
+// weightOf returns the weight of the first sequence the same as s, or
+// one if there are no weights or no such sequence.
+func (seqgrp *SeqGrp) weightOf(s []byte) float32 {
+	if seqgrp.seqWt == nil {
+		return 1
+	}
+	for i, ss := range seqgrp.seqs {
+		if bytes.Equal(ss.seq, s) {
+			return seqgrp.seqWt[i]
+		}
+	}
+	return 1
+}
+
+// HenikoffWeights returns position-based weights. At a site with r
+// different symbols, where symbol c is found n_c times, a sequence with
+// c gets 1/(r n_c). A sequence's weight is the sum over all sites. Gaps
+// count as a symbol, as in the original paper. Weights are scaled to
+// add up to the number of sequences, so weighted counts are on the same
+// scale as plain ones. The symbols are remapped by the alphabet.
+// Pass the result to SetSeqWeights.
+func (seqgrp *SeqGrp) HenikoffWeights() []float32 {
+	if len(seqgrp.revmap) == 0 {
+		seqgrp.mapsyms()
+	}
+	nrow, ncol := len(seqgrp.revmap), seqgrp.GetLen()
+	raw := matrix.NewIMatrix2d(nrow, ncol).Mat
+	seqgrp.countShards(raw, nil)
+	nDiff := make([]int32, ncol) // r, different symbols at each site
+	for irow := range raw {
+		for icol, n := range raw[irow] {
+			if n != 0 {
+				nDiff[icol]++
+			}
+		}
+	}
+	inv := matrix.NewFMatrix2d(nrow, ncol).Mat // 1/(r n_c)
+	for irow := range raw {
+		for icol, n := range raw[irow] {
+			if n != 0 {
+				inv[irow][icol] = 1 / float32(nDiff[icol]*n)
+			}
+		}
+	}
+	remap := seqgrp.remapTable()
+	wt := make([]float32, len(seqgrp.seqs))
+	var total float64
+	for is, ss := range seqgrp.seqs {
+		var w float64
+		for icol, c := range ss.seq {
+			w += float64(inv[seqgrp.mapping[remap[c]]][icol])
+		}
+		wt[is] = float32(w)
+		total += w
+	}
+	if total == 0 {
+		return wt
+	}
+	scale := float64(len(wt)) / total
+	for i := range wt {
+		wt[i] = float32(float64(wt[i]) * scale)
+	}
+	return wt
+}
@@ -0,0 +1,60 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestHenikoff works out the weights by hand. Column 0 is all A, so
+// each gets 1/3. In column 1, each A gets 1/(2*2) and the B gets 1/2.
+// The sums, 7/12, 7/12 and 10/12, are scaled to add up to 3.
+func TestHenikoff(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AA", "AA", "AB"})
+	w := seqgrp.HenikoffWeights()
+	if !sliceEql(w, []float32{7. / 8, 7. / 8, 5. / 4}) {
+		t.Fatal("weights wrong", w)
+	}
+	if err := seqgrp.SetSeqWeights(w[:2]); err == nil {
+		t.Fatal("two weights for three sequences should fail")
+	}
+	if err := seqgrp.SetSeqWeights(w); err != nil {
+		t.Fatal(err)
+	}
+	p := newProfile(t, seqgrp, false)
+	if p.Count('B', 1) != 1 || !roughEql(p.Freq('B', 1), 5./12) {
+		t.Fatal("weighted frequency of B wanted 5/12, got", p.Freq('B', 1))
+	}
+	ss := seqgrp.SeqSlc()
+	if c := compat(t, seqgrp, ss[2].GetSeq(), false); c[0] != 1 || c[1] != 0 {
+		t.Fatal("compatibility of AB", c)
+	}
+	if c := compat(t, seqgrp, ss[0].GetSeq(), false); !roughEql(c[1], 7./17) {
+		t.Fatal("compatibility of AA at 1 wanted 7/17, got", c[1])
+	}
+	seqgrp.SetSeqWeights(nil)
+	if f := newProfile(t, seqgrp, false).Freq('B', 1); !roughEql(f, 1./3) {
+		t.Fatal("without weights, B wanted 1/3, got", f)
+	}
+}
+
+// TestWeightsChanged drops a sequence after SetSeqWeights. Counting
+// should then fail, rather than use the wrong weights.
+func TestWeightsChanged(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"AA", "AA", "AB"})
+	if err := seqgrp.SetSeqWeights(seqgrp.HenikoffWeights()); err != nil {
+		t.Fatal(err)
+	}
+	seqgrp.DropSeqs(2)
+	if _, err := NewProfile(seqgrp, false); err == nil {
+		t.Fatal("two sequences with three weights should fail")
+	}
+	if err := seqgrp.Entropy(false, make([]float32, 2)); err == nil {
+		t.Fatal("Entropy should fail as well")
+	}
+	if err := seqgrp.UsageSite(); err == nil {
+		t.Fatal("UsageSite should fail as well")
+	}
+}
@@ -0,0 +1,147 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// stk1 is interleaved, has "." gaps, descriptions and both kinds of
+// annotation, split across the blocks.
+var stk1 = `# STOCKHOLM 1.0
+#=GF ID   test_family
+#=GS s1 DE first sequence
+
+s1          ACDE.F
+#=GR s1 SS  HHHH.E
+s2          ACDEGF
+#=GC SS_cons HHH.HE
+#=GC RF      xxx.xx
+
+s1          GH-K
+#=GR s1 SS  EE-C
+s2          GHIK
+#=GC SS_cons EE.C
+#=GC RF      xx.x
+//
+s3          this should never be read
+`
+
+// TestStockholm reads a small interleaved file and checks the sequences
+// and the annotation tracks.
+func TestStockholm(t *testing.T) {
+	var seqgrp SeqGrp
+	if err := ReadStockholm(strings.NewReader(stk1), &seqgrp, &Options{}); err != nil {
+		t.Fatal("reading stockholm", err)
+	}
+	if n := seqgrp.NSeq(); n != 2 {
+		t.Fatalf("stockholm wanted 2 seqs, got %d", n)
+	}
+	slc := seqgrp.SeqSlc()
+	wantSeq := []string{"ACDE-FGH-K", "ACDEGFGHIK"}
+	wantCmmt := []string{"s1 first sequence", "s2"}
+	for i := range wantSeq {
+		if s := string(slc[i].GetSeq()); s != wantSeq[i] {
+			t.Fatalf("seq %d wanted %s got %s", i, wantSeq[i], s)
+		}
+		cmmtHelp(slc[i].Cmmt(), wantCmmt[i], t)
+	}
+	if s := string(seqgrp.ColAnnot("SS_cons")); s != "HHH.HEEE.C" {
+		t.Fatal("SS_cons got", s)
+	}
+	if s := string(seqgrp.ColAnnot("RF")); s != "xxx.xxxx.x" {
+		t.Fatal("RF got", s)
+	}
+	if tags := seqgrp.ColAnnotTags(); len(tags) != 2 || tags[0] != "RF" {
+		t.Fatal("column annotation tags got", tags)
+	}
+	if s := string(seqgrp.SeqAnnot(0, "SS")); s != "HHHH.EEE-C" {
+		t.Fatal("s1 SS got", s)
+	}
+	if seqgrp.SeqAnnot(1, "SS") != nil {
+		t.Fatal("s2 should not have annotation")
+	}
+}
+
+// TestStockholmOpts checks that a range and gap removal also act on the
+// annotation.
+func TestStockholmOpts(t *testing.T) {
+	var seqgrp SeqGrp
+	s_opts := &Options{RangeStart: 2, RangeEnd: 5}
+	if err := ReadStockholm(strings.NewReader(stk1), &seqgrp, s_opts); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(seqgrp.SeqSlc()[1].GetSeq()); s != "DEGF" {
+		t.Fatal("range got", s)
+	}
+	if s := string(seqgrp.ColAnnot("SS_cons")); s != "H.HE" {
+		t.Fatal("range on SS_cons got", s)
+	}
+
+	var seqgrp2 SeqGrp
+	s_opts = &Options{RmvGapsRd: true, DiffLenSeq: true}
+	if err := ReadStockholm(strings.NewReader(stk1), &seqgrp2, s_opts); err != nil {
+		t.Fatal(err)
+	}
+	if s := string(seqgrp2.SeqAnnot(0, "SS")); s != "HHHHEEEC" {
+		t.Fatal("gap removal on GR got", s)
+	}
+
+	for _, r := range [][2]int{{5, 2}, {-1, 2}} {
+		var seqgrp3 SeqGrp
+		s_opts = &Options{RangeStart: r[0], RangeEnd: r[1]}
+		if err := ReadStockholm(strings.NewReader(stk1), &seqgrp3, s_opts); err == nil {
+			t.Fatal("range", r, "should fail")
+		}
+		if err := ReadFasta(strings.NewReader(">s1\nACDEFGH\n"), &seqgrp3, s_opts); err == nil {
+			t.Fatal("fasta range", r, "should fail")
+		}
+	}
+}
+
+// TestStockholmReadfile checks that Readfile recognises the format.
+func TestStockholmReadfile(t *testing.T) {
+	tmpname, err := wrtTmp(stk1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpname)
+	seqgrp, err := Readfile(tmpname, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seqgrp.NSeq() != 2 || seqgrp.GetLen() != 10 {
+		t.Fatal("Readfile on stockholm got", seqgrp.NSeq(), "seqs")
+	}
+}
+
+// TestStockholmBroken checks that unequal lengths are caught.
+func TestStockholmBroken(t *testing.T) {
+	broken := "# STOCKHOLM 1.0\ns1 ACDE\ns2 ACD\n//\n"
+	var seqgrp SeqGrp
+	if err := ReadStockholm(strings.NewReader(broken), &seqgrp, &Options{}); err == nil {
+		t.Fatal("should fail on different length sequences")
+	}
+}
+
+// TestStockholmOrphanGR has a #=GR line for a name with no sequence. It
+// should give a ParseError pointing at the #=GR line, not an empty
+// sequence.
+func TestStockholmOrphanGR(t *testing.T) {
+	orphan := "# STOCKHOLM 1.0\ns1 ACDE\n#=GR s1 SS HHHE\n#=GR s9 SS HHHE\ns2 ACDF\n//\n"
+	var seqgrp SeqGrp
+	err := ReadStockholm(strings.NewReader(orphan), &seqgrp, &Options{})
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatal("orphan #=GR wanted ParseError, got", err)
+	}
+	if pe.Record != 1 || pe.Header != "s9" || pe.Line != 4 {
+		t.Fatalf("orphan #=GR wanted record 1 \"s9\" line 4, got %d \"%s\" line %d",
+			pe.Record, pe.Header, pe.Line)
+	}
+}
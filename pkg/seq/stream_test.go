@@ -0,0 +1,116 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// pipe hides the Seek method of a reader, so it looks like stdin
+// connected to a pipe.
+type pipe struct{ io.Reader }
+
+// manySeqs makes n sequences of the same length, each different, so we
+// would notice if one were overwritten.
+func manySeqs(n int) (string, []string) {
+	var b strings.Builder
+	want := make([]string, n)
+	for i := range want {
+		want[i] = fmt.Sprintf("AC%08dGH", i)
+		fmt.Fprintf(&b, "> s%d\n%s\n", i, want[i])
+	}
+	return b.String(), want
+}
+
+// TestStream reads more sequences than fit in the first block from
+// something that cannot seek, with and without a range.
+func TestStream(t *testing.T) {
+	const nseq = 5000
+	s, want := manySeqs(nseq)
+	for _, s_opts := range []*Options{{}, {RangeStart: 2, RangeEnd: 9}} {
+		var seqgrp SeqGrp
+		if err := ReadFasta(pipe{strings.NewReader(s)}, &seqgrp, s_opts); err != nil {
+			t.Fatal(err)
+		}
+		if seqgrp.NSeq() != nseq {
+			t.Fatal("wanted", nseq, "seqs, got", seqgrp.NSeq())
+		}
+		for i, ss := range seqgrp.SeqSlc() {
+			w := want[i]
+			if s_opts.RangeEnd != 0 {
+				w = w[s_opts.RangeStart : s_opts.RangeEnd+1]
+			}
+			if string(ss.GetSeq()) != w {
+				t.Fatal("seq", i, "wanted", w, "got", string(ss.GetSeq()))
+			}
+		}
+	}
+	var seqgrp SeqGrp
+	broken := s + "> bad\nACDE\n"
+	if err := ReadFasta(pipe{strings.NewReader(broken)}, &seqgrp, &Options{}); err == nil {
+		t.Fatal("should fail on different length sequence")
+	}
+}
+
+// dribble gives at most n bytes per Read, like a pipe or a
+// decompressor which has not got any more yet.
+type dribble struct {
+	io.Reader
+	n int
+}
+
+func (d dribble) Read(p []byte) (int, error) {
+	return d.Reader.Read(p[:min(len(p), d.n)])
+}
+
+// TestShortReads gives the lexer less than it asks for each time. A
+// short read is not the end of the input.
+func TestShortReads(t *testing.T) {
+	s, want := manySeqs(500)
+	for _, n := range []int{1, 7, 100, 4095} {
+		var seqgrp SeqGrp
+		if err := ReadFasta(dribble{strings.NewReader(s), n}, &seqgrp, &Options{}); err != nil {
+			t.Fatal("reading", n, "bytes at a time:", err)
+		}
+		if seqgrp.NSeq() != len(want) {
+			t.Fatal("wanted", len(want), "seqs, got", seqgrp.NSeq())
+		}
+		for i, ss := range seqgrp.SeqSlc() {
+			if string(ss.GetSeq()) != want[i] {
+				t.Fatal(n, "bytes at a time, seq", i, "wanted", want[i], "got", string(ss.GetSeq()))
+			}
+		}
+	}
+}
+
+// TestReadStdin puts a file on standard input, like "cat x.fa | entropy"
+func TestReadStdin(t *testing.T) {
+	s, want := manySeqs(50)
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		io.WriteString(pw, s)
+		pw.Close()
+	}()
+	oldStdin := os.Stdin
+	os.Stdin = pr
+	defer func() { os.Stdin = oldStdin }()
+	seqgrp, err := Readfile("-", &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seqgrp.NSeq() != len(want) {
+		t.Fatal("wanted", len(want), "seqs, got", seqgrp.NSeq())
+	}
+	if got := string(seqgrp.SeqSlc()[49].GetSeq()); got != want[49] {
+		t.Fatal("wanted", want[49], "got", got)
+	}
+}
@@ -0,0 +1,114 @@
+// 17 Oct 2026
+// Writing fasta. A Writer wraps any io.Writer (a file, standard output
+// or a buffer in a test) and buffers the output. The line width, case,
+// gap removal and comment lines can be changed before writing.
+// Like bufio.Writer, the first error sticks. Everything after it is
+// thrown away and the error comes back from every later call.
+
+package seq
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+)
+
+// LetterCase says what to do with the case of residues on output.
+type LetterCase byte
+
+const (
+	KeepCase  LetterCase = iota // Write residues as they are
+	UpperCase                   // Convert residues to upper case
+	LowerCase                   // Convert residues to lower case
+)
+
+// DefaultLineWidth is the number of residues per line from NewWriter.
+const DefaultLineWidth = 60
+
+// Writer writes sequences in fasta format. Set the fields after
+// NewWriter and before the first sequence is written.
+type Writer struct {
+	LineWidth int                      // residues per line, 0 for one line per sequence
+	Case      LetterCase               // case conversion of residues
+	RmvGaps   bool                     // leave out gap characters
+	Header    func(cmmt string) string // if not nil, rewrites comment lines
+	bw        *bufio.Writer
+	scratch   []byte // for sequences which have to be changed
+	err       error
+}
+
+// NewWriter returns a Writer with DefaultLineWidth which changes nothing.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{LineWidth: DefaultLineWidth, bw: bufio.NewWriter(w)}
+}
+
+// convert applies gap removal and case conversion. It only copies if
+// there is something to do.
+func (w *Writer) convert(s []byte) []byte {
+	if !w.RmvGaps && w.Case == KeepCase {
+		return s
+	}
+	t := w.scratch[:0]
+	for _, c := range s {
+		if w.RmvGaps && c == common.GapChar {
+			continue
+		}
+		switch {
+		case w.Case == UpperCase && c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		case w.Case == LowerCase && c >= 'A' && c <= 'Z':
+			c += 'a' - 'A'
+		}
+		t = append(t, c)
+	}
+	w.scratch = t
+	return t
+}
+
+// WriteSeq writes one sequence. Empty sequences are skipped.
+func (w *Writer) WriteSeq(s seq) error {
+	if w.err != nil {
+		return w.err
+	}
+	if s.Empty() {
+		return nil
+	}
+	cmmt := s.Cmmt()
+	if w.Header != nil {
+		cmmt = w.Header(cmmt)
+	}
+	w.bw.WriteByte(cmmt_char)
+	w.bw.WriteString(cmmt)
+	w.bw.WriteByte('\n')
+	b := w.convert(s.GetSeq())
+	if w.LineWidth > 0 {
+		for ; len(b) > w.LineWidth; b = b[w.LineWidth:] {
+			w.bw.Write(b[:w.LineWidth])
+			w.bw.WriteByte('\n')
+		}
+	}
+	w.bw.Write(b)
+	_, w.err = w.bw.Write([]byte{'\n'})
+	return w.err
+}
+
+// WriteGrp writes all the sequences in seqgrp.
+func (w *Writer) WriteGrp(seqgrp *SeqGrp) error {
+	for _, s := range seqgrp.seqs {
+		if err := w.WriteSeq(s); err != nil {
+			return err
+		}
+	}
+	return w.err
+}
+
+// Flush writes anything still in the buffer. It must be called after
+// the last sequence.
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.bw.Flush()
+	return w.err
+}
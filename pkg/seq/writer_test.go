@@ -0,0 +1,75 @@
+// 17 Oct 2026
+
+package seq_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	. "github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// TestWriter tries each of the settings on a buffer.
+func TestWriter(t *testing.T) {
+	seqgrp := Str2SeqGrp([]string{"ACd-EfGH", "a--c"})
+	tests := []struct {
+		name string
+		set  func(*Writer)
+		want string
+	}{
+		{"default", func(w *Writer) {}, ">s0\nACd-EfGH\n>s1\na--c\n"},
+		{"width 3", func(w *Writer) { w.LineWidth = 3 }, ">s0\nACd\n-Ef\nGH\n>s1\na--\nc\n"},
+		{"unwrapped", func(w *Writer) { w.LineWidth = 0 }, ">s0\nACd-EfGH\n>s1\na--c\n"},
+		{"upper", func(w *Writer) { w.Case = UpperCase }, ">s0\nACD-EFGH\n>s1\nA--C\n"},
+		{"lower no gaps", func(w *Writer) { w.Case, w.RmvGaps = LowerCase, true }, ">s0\nacdefgh\n>s1\nac\n"},
+		{"header", func(w *Writer) { w.Header = func(s string) string { return "x" + s } }, ">xs0\nACd-EfGH\n>xs1\na--c\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		tt.set(w)
+		if err := w.WriteGrp(seqgrp); err != nil {
+			t.Fatal(tt.name, err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(tt.name, err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%s: wanted %q got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+// failWriter fails after n bytes.
+type failWriter struct{ n int }
+
+var errFull = errors.New("disk full")
+
+func (f *failWriter) Write(b []byte) (int, error) {
+	if len(b) > f.n {
+		n := f.n
+		f.n = 0
+		return n, errFull
+	}
+	f.n -= len(b)
+	return len(b), nil
+}
+
+// TestWriterError checks a write error comes back and sticks.
+func TestWriterError(t *testing.T) {
+	long := strings.Repeat("A", 10000)
+	seqgrp := Str2SeqGrp([]string{long, long})
+	w := NewWriter(&failWriter{n: 100})
+	err := w.WriteGrp(seqgrp)
+	if err == nil {
+		err = w.Flush()
+	}
+	if !errors.Is(err, errFull) {
+		t.Fatal("wanted disk full error, got", err)
+	}
+	if err := w.Flush(); !errors.Is(err, errFull) {
+		t.Fatal("error should stick, got", err)
+	}
+}
@@ -3,7 +3,14 @@
 // For each sequence, get the comment, species name and write send
 // create a file for a spreadsheet.
 // This will have the gene_id, the species name and the length
-// of the sequence without gaps.
+// of the sequence without gaps. The gene_id (accession) and species
+// come from seq.Header, which knows about UniProt and NCBI comments, or
+// from a template given by the user.
+// This is a change. The first column used to be the first word of the
+// comment. Now it is the accession, so ">sp|P69905|HBA_HUMAN ..." gives
+// P69905 and ">gi|4504347|ref|NP_000549.1| ..." gives NP_000549.1. For
+// plain NCBI comments, it is still the first word. The species is
+// the last thing in square brackets, or the OS= field from UniProt.
 
 package seqlen
 
@@ -13,7 +20,6 @@ import (
 	"io"
 	"os"
 	"strconv"
-	"strings"
 
 	"github.com/andrew-torda/seq_compat/pkg/seq"
 )
@@ -30,28 +36,26 @@ type CmdArgs struct {
 	OutCntFname string // Write counts (sequence lengths) to here
 	OutSeqFname string // Optionally write sequences without gaps here
 	IgnrSeqLen  bool   // Do not worry about sequences having different lengths
-}
-
-// nameSpecies gets the name and species from a comment
-// Name is just the first word in the comment.
-// Species is any text between [ and ].
-func nameSpecies(cmmt string) (name string, species string) {
-	name = strings.Fields(cmmt) [0] // Get the first word
-	if _, after, found1 := strings.Cut(cmmt, "["); found1 {
-		if before, _, found2 := strings.Cut(after, "]"); found2 {
-			species = before
-		}
-	}
-	return name, species
+	Template    string // Optional regular expression for comment lines
+	InFormat    string // Input format, guessed from the file if empty
+	OutFormat   string // Format for OutSeqFname, default fasta
 }
 
 // writeLen reads a sequence file, visits each sequence in turn
 // and writes the length (and species) to an output (csv) file.
 func writeLen(cmdArgs CmdArgs, outCntFile io.Writer) error {
-	s_opts := &seq.Options{RmvGapsRd: true, ZeroLenOK: true}
+	s_opts := &seq.Options{RmvGapsRd: true, ZeroLenOK: true,
+		InFormat: cmdArgs.InFormat, OutFormat: cmdArgs.OutFormat}
 	if cmdArgs.IgnrSeqLen == true {
 		s_opts.DiffLenSeq = true
 	}
+	if cmdArgs.Template != "" {
+		tmpl, err := seq.NewHeaderTemplate(cmdArgs.Template)
+		if err != nil {
+			return err
+		}
+		s_opts.Templates = append(s_opts.Templates, tmpl)
+	}
 	seqgrp, err := seq.Readfile(cmdArgs.InSeqFname, s_opts)
 	if err != nil {
 		return fmt.Errorf("Fail reading sequences: %w", err)
@@ -61,9 +65,7 @@ func writeLen(cmdArgs CmdArgs, outCntFile io.Writer) error {
 
 	for _, seq := range seqgrp.SeqSlc() {
 		len := strconv.Itoa(len(seq.GetSeq()))
-		cmmt := seq.Cmmt()
-		name, species := nameSpecies(cmmt)
-		s3 := [3]string{name, len, species}
+		s3 := [3]string{seq.Accession(), len, seq.Organism()}
 
 		if err := csvDst.Write(s3[:]); err != nil {
 			return err
@@ -73,7 +75,7 @@ func writeLen(cmdArgs CmdArgs, outCntFile io.Writer) error {
 
 	if cmdArgs.OutSeqFname != ""{
 		osf:= cmdArgs.OutSeqFname // readability
-		if err := seq.WriteToF(osf, seqgrp.SeqSlc(), s_opts); err != nil{
+		if err := seq.Writefile(osf, seqgrp, s_opts); err != nil{
 			e := fmt.Errorf("csv file OK, but error writing seqs: %w", err)
 			return e
 		}
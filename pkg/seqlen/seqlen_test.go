@@ -0,0 +1,53 @@
+// 17 Oct 2026
+
+package seqlen_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq/common"
+	"github.com/andrew-torda/seq_compat/pkg/seqlen"
+)
+
+// headers has one UniProt, one old NCBI and one plain comment.
+const headers = `> sp|P69905|HBA_HUMAN Hemoglobin alpha OS=Homo sapiens OX=9606
+AC-D
+> gi|4504347|ref|NP_000549.1| hemoglobin [Homo sapiens]
+A--D
+> xyz.123 comment here [mus musculus]
+ACGD
+`
+
+// TestAccession checks the first column is the accession, not the
+// first word of the comment, and the species comes from OS= as well
+// as brackets. A template takes the accession from somewhere else.
+func TestAccession(t *testing.T) {
+	fname, err := common.WrtTemp(headers)
+	if err != nil {
+		t.Fatal("Fail writing test file")
+	}
+	defer os.Remove(fname)
+	for _, tc := range []struct {
+		template, want string
+	}{
+		{"", "P69905,3,Homo sapiens\nNP_000549.1,2,Homo sapiens\nxyz.123,4,mus musculus\n"},
+		{`^\S+ (?P<accession>comment)`,
+			"P69905,3,Homo sapiens\nNP_000549.1,2,Homo sapiens\ncomment,4,\n"},
+	} {
+		outName := filepath.Join(t.TempDir(), "lens.csv")
+		cmdArgs := seqlen.CmdArgs{InSeqFname: fname, OutCntFname: outName,
+			IgnrSeqLen: true, Template: tc.template}
+		if err := seqlen.Mymain(cmdArgs); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(outName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Fatalf("template %q\nwanted\n%s\ngot\n%s", tc.template, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// 17 Oct 2026
+// seqlint reads an alignment and looks for everything that might upset
+// the conservation programs later. Readfile stops at the first problem,
+// so we read with the options which allow anything (different lengths,
+// empty sequences) and then look at each problem in turn, so all of
+// them are reported at once.
+// Sequences and columns are numbered from one in the report.
+
+package seqlint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq"
+)
+
+// Kinds of issue.
+const (
+	KindLength    = "length"       // not the same length as most sequences
+	KindSymbol    = "symbol"       // not a letter, gap or "*", or not ascii
+	KindDupID     = "duplicate-id" // same accession as another sequence
+	KindIdentical = "identical"    // same sequence as another
+	KindEmpty     = "empty"        // no residues, perhaps only gaps
+	KindGapColumn = "gap-column"   // every sequence has a gap
+	KindMixedGaps = "mixed-gaps"   // more than one gap character
+	KindType      = "type"         // the type guess looks wrong
+)
+
+// gapChars are the characters different programs use for gaps.
+var gapChars = []byte{'-', '.', '~'}
+
+// Issue is one problem. Records are the sequences involved. Column and
+// EndColumn are a range of columns. Unused fields are zero.
+type Issue struct {
+	Kind      string `json:"kind"`
+	Records   []int  `json:"records,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndColumn int    `json:"end_column,omitempty"`
+	Msg       string `json:"message"`
+}
+
+// Report is everything we found in one file.
+type Report struct {
+	File   string  `json:"file"`
+	NSeq   int     `json:"nseq"`
+	Length int     `json:"length"` // most common length
+	Type   string  `json:"type"`
+	Issues []Issue `json:"issues"`
+}
+
+// CmdArgs are the command line arguments.
+type CmdArgs struct {
+	InSeqFname string // Read sequences from here
+	OutFname   string // Write the report here, "" or "-" for stdout
+	InFormat   string // Input format, guessed from the file if empty
+	JSON       bool   // Write JSON instead of text
+}
+
+// isGap says if c is any of the gap characters.
+func isGap(c byte) bool {
+	for _, g := range gapChars {
+		if c == g {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName is for printing sequence types.
+func typeName(t seq.SeqType) string {
+	switch t {
+	case seq.Protein:
+		return "protein"
+	case seq.DNA:
+		return "DNA"
+	case seq.RNA:
+		return "RNA"
+	case seq.Ntide:
+		return "nucleotide"
+	}
+	return "unknown"
+}
+
+// add appends an issue.
+func (r *Report) add(kind string, records []int, format string, a ...interface{}) *Issue {
+	r.Issues = append(r.Issues, Issue{Kind: kind, Records: records, Msg: fmt.Sprintf(format, a...)})
+	return &r.Issues[len(r.Issues)-1]
+}
+
+// modeLen is the most common sequence length. Ties go to the longer.
+func modeLen(seqs []string) int {
+	count := make(map[int]int)
+	mode := 0
+	for _, s := range seqs {
+		l := len(s)
+		count[l]++
+		if count[l] > count[mode] || (count[l] == count[mode] && l > mode) {
+			mode = l
+		}
+	}
+	return mode
+}
+
+// lengths complains about sequences which are not the usual length.
+func (r *Report) lengths(seqs []string) {
+	for i, s := range seqs {
+		if len(s) != r.Length {
+			r.add(KindLength, []int{i + 1}, "sequence %d has length %d, most have %d", i+1, len(s), r.Length)
+		}
+	}
+}
+
+// symbols looks for characters which are not letters, gaps or "*".
+// There is one issue per sequence, with the first bad position.
+func (r *Report) symbols(seqs []string) {
+	for i, s := range seqs {
+		first := -1
+		var bad []string
+		seen := make(map[byte]bool)
+		for j := 0; j < len(s); j++ {
+			c := s[j]
+			if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || c == '*' || isGap(c) {
+				continue
+			}
+			if first == -1 {
+				first = j
+			}
+			if !seen[c] {
+				seen[c] = true
+				if c >= 0x80 {
+					bad = append(bad, fmt.Sprintf("0x%02x (not ascii)", c))
+				} else {
+					bad = append(bad, fmt.Sprintf("%q", c))
+				}
+			}
+		}
+		if first != -1 {
+			const msg = "sequence %d has bad symbols %s, first at column %d"
+			is := r.add(KindSymbol, []int{i + 1}, msg, i+1, strings.Join(bad, " "), first+1)
+			is.Column = first + 1
+		}
+	}
+}
+
+// groups finds sets of records with the same key. Empty keys are not
+// interesting.
+func groups(keys []string) [][]int {
+	byKey := make(map[string][]int)
+	var order []string
+	for i, k := range keys {
+		if k == "" {
+			continue
+		}
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = append(byKey[k], i+1)
+	}
+	var ret [][]int
+	for _, k := range order {
+		if len(byKey[k]) > 1 {
+			ret = append(ret, byKey[k])
+		}
+	}
+	return ret
+}
+
+// joinInts is for messages listing sequence numbers.
+func joinInts(ii []int) string {
+	s := make([]string, len(ii))
+	for i, n := range ii {
+		s[i] = fmt.Sprint(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// duplicates looks for repeated accessions and repeated sequences.
+func (r *Report) duplicates(seqgrp *seq.SeqGrp, seqs []string) {
+	ids := make([]string, len(seqs))
+	for i, ss := range seqgrp.SeqSlc() {
+		ids[i] = ss.Header().Accession
+	}
+	for _, g := range groups(ids) {
+		r.add(KindDupID, g, "sequences %s have the same id \"%s\"", joinInts(g), ids[g[0]-1])
+	}
+	for _, g := range groups(seqs) {
+		r.add(KindIdentical, g, "sequences %s are identical", joinInts(g))
+	}
+}
+
+// empties looks for sequences with no residues.
+func (r *Report) empties(seqs []string) {
+	for i, s := range seqs {
+		empty := true
+		for j := 0; j < len(s) && empty; j++ {
+			empty = isGap(s[j])
+		}
+		if empty {
+			r.add(KindEmpty, []int{i + 1}, "sequence %d has no residues", i+1)
+		}
+	}
+}
+
+// gapColumns looks for columns which are gaps in every sequence of the
+// usual length. Runs of columns are one issue.
+func (r *Report) gapColumns(seqs []string) {
+	var aligned []string
+	for _, s := range seqs {
+		if len(s) == r.Length {
+			aligned = append(aligned, s)
+		}
+	}
+	if len(aligned) == 0 {
+		return
+	}
+	allGap := func(col int) bool {
+		for _, s := range aligned {
+			if !isGap(s[col]) {
+				return false
+			}
+		}
+		return true
+	}
+	for col := 0; col < r.Length; col++ {
+		if !allGap(col) {
+			continue
+		}
+		end := col
+		for end+1 < r.Length && allGap(end+1) {
+			end++
+		}
+		var is *Issue
+		if end == col {
+			is = r.add(KindGapColumn, nil, "column %d is all gaps", col+1)
+		} else {
+			is = r.add(KindGapColumn, nil, "columns %d to %d are all gaps", col+1, end+1)
+		}
+		is.Column, is.EndColumn = col+1, end+1
+		col = end
+	}
+}
+
+// mixedGaps complains if more than one gap character is used.
+func (r *Report) mixedGaps(seqs []string) {
+	var count [256]int
+	for _, s := range seqs {
+		for j := 0; j < len(s); j++ {
+			count[s[j]]++
+		}
+	}
+	var used []string
+	for _, g := range gapChars {
+		if count[g] > 0 {
+			used = append(used, fmt.Sprintf("\"%c\" %d times", g, count[g]))
+		}
+	}
+	if len(used) > 1 {
+		r.add(KindMixedGaps, nil, "more than one gap character, %s", strings.Join(used, ", "))
+	}
+}
+
+// seqType guesses the type, as the other programs would after
+// converting to upper case, and says if the guess looks shaky. The most
+// common case is nucleotides with a few ambiguity codes like N or R,
+// which make the guess go to protein.
+func (r *Report) seqType(seqs []string) {
+	const ntides = "ACGTUN"
+	var clean []string
+	var used [256]bool
+	nRes, nNtide := 0, 0
+	for _, s := range seqs {
+		b := make([]byte, 0, len(s))
+		for j := 0; j < len(s); j++ {
+			c := s[j]
+			if 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			if c < 'A' || c > 'Z' {
+				continue
+			}
+			b = append(b, c)
+			used[c] = true
+			nRes++
+			if strings.IndexByte(ntides, c) != -1 {
+				nNtide++
+			}
+		}
+		clean = append(clean, string(b))
+	}
+	stype := seq.Str2SeqGrp(clean).GetType()
+	r.Type = typeName(stype)
+	switch {
+	case nRes == 0:
+		r.add(KindType, nil, "no residues, cannot tell the sequence type")
+	case stype == seq.Unknown:
+		r.add(KindType, nil, "cannot tell if these are protein or nucleotide sequences")
+	case stype == seq.Protein && nNtide*10 >= nRes*9:
+		const msg = "guessed protein, but %.0f%% of residues are ACGTUN, nucleotides with ambiguity codes?"
+		r.add(KindType, nil, msg, 100*float64(nNtide)/float64(nRes))
+	case stype == seq.Ntide && used['T'] && used['U']:
+		r.add(KindType, nil, "nucleotides with both T and U")
+	}
+}
+
+// Lint runs all the checks on seqgrp.
+func Lint(seqgrp *seq.SeqGrp) *Report {
+	seqs := make([]string, seqgrp.NSeq())
+	for i, ss := range seqgrp.SeqSlc() {
+		seqs[i] = string(ss.GetSeq())
+	}
+	r := &Report{NSeq: len(seqs), Length: modeLen(seqs), Issues: []Issue{}}
+	r.lengths(seqs)
+	r.symbols(seqs)
+	r.duplicates(seqgrp, seqs)
+	r.empties(seqs)
+	r.gapColumns(seqs)
+	r.mixedGaps(seqs)
+	r.seqType(seqs)
+	sort.SliceStable(r.Issues, func(i, j int) bool { return r.Issues[i].Kind < r.Issues[j].Kind })
+	return r
+}
+
+// WriteText writes the report for people to read.
+func (r *Report) WriteText(w io.Writer) error {
+	const hdr = "%s: %d sequences, length %d, type %s\n"
+	fmt.Fprintf(w, hdr, r.File, r.NSeq, r.Length, r.Type)
+	for _, is := range r.Issues {
+		fmt.Fprintf(w, "%s: %s\n", is.Kind, is.Msg)
+	}
+	_, err := fmt.Fprintf(w, "%d problems found\n", len(r.Issues))
+	return err
+}
+
+// WriteJSON writes the report as JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// Mymain reads the file, runs the checks and writes the report. It
+// returns the number of problems found.
+func Mymain(cmdArgs CmdArgs) (int, error) {
+	s_opts := &seq.Options{DiffLenSeq: true, ZeroLenOK: true, InFormat: cmdArgs.InFormat}
+	seqgrp, err := seq.Readfile(cmdArgs.InSeqFname, s_opts)
+	if err != nil {
+		return 0, err
+	}
+	r := Lint(seqgrp)
+	r.File = cmdArgs.InSeqFname
+	if r.File == "" || r.File == "-" {
+		r.File = "standard input"
+	}
+	write := r.WriteText
+	if cmdArgs.JSON {
+		write = r.WriteJSON
+	}
+	if cmdArgs.OutFname == "" || cmdArgs.OutFname == "-" {
+		return len(r.Issues), write(os.Stdout)
+	}
+	fp, err := os.Create(cmdArgs.OutFname)
+	if err != nil {
+		return 0, fmt.Errorf("report file: %w", err)
+	}
+	if err := write(fp); err != nil {
+		fp.Close()
+		return 0, err
+	}
+	return len(r.Issues), fp.Close()
+}
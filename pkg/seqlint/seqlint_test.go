@@ -0,0 +1,82 @@
+// 17 Oct 2026
+
+package seqlint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/seq"
+	"github.com/andrew-torda/seq_compat/pkg/seqlint"
+)
+
+// One sequence of each sort of problem. Column 6 is all gaps.
+const bad = "> a\nACDEF-GH\n> a\nACDEF-GH\n> c\nACD\n" +
+	"> d\nAC1EF-G\xe9\n> e\n-----.--\n> f\nACDKL~MN\n"
+
+// TestLint checks every kind of issue is found in one pass.
+func TestLint(t *testing.T) {
+	var seqgrp seq.SeqGrp
+	s_opts := &seq.Options{DiffLenSeq: true, ZeroLenOK: true}
+	if err := seq.ReadFasta(strings.NewReader(bad), &seqgrp, s_opts); err != nil {
+		t.Fatal(err)
+	}
+	r := seqlint.Lint(&seqgrp)
+	found := make(map[string]int)
+	for _, is := range r.Issues {
+		found[is.Kind]++
+	}
+	want := map[string]int{
+		seqlint.KindLength:    1,
+		seqlint.KindSymbol:    1,
+		seqlint.KindDupID:     1,
+		seqlint.KindIdentical: 1,
+		seqlint.KindEmpty:     1,
+		seqlint.KindGapColumn: 1,
+		seqlint.KindMixedGaps: 1,
+	}
+	for k, n := range want {
+		if found[k] != n {
+			t.Errorf("wanted %d of %s, got %d", n, k, found[k])
+		}
+	}
+	for _, is := range r.Issues {
+		switch is.Kind {
+		case seqlint.KindSymbol:
+			if is.Column != 3 || is.Records[0] != 4 || !strings.Contains(is.Msg, "not ascii") {
+				t.Errorf("symbol issue wrong %+v", is)
+			}
+		case seqlint.KindGapColumn:
+			if is.Column != 6 || is.EndColumn != 6 {
+				t.Errorf("gap column wrong %+v", is)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var back seqlint.Report
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Issues) != len(r.Issues) || back.NSeq != 6 {
+		t.Fatal("json round trip lost issues")
+	}
+}
+
+// TestType checks DNA with ambiguity codes is flagged.
+func TestType(t *testing.T) {
+	ss := []string{"ACGTNACGTRACGTACGTAC", "ACGTACGTACGTACGTACGT"}
+	r := seqlint.Lint(seq.Str2SeqGrp(ss))
+	if len(r.Issues) != 1 || r.Issues[0].Kind != seqlint.KindType {
+		t.Fatalf("wanted one type issue, got %+v", r.Issues)
+	}
+	r = seqlint.Lint(seq.Str2SeqGrp([]string{"ACDEFGHIKL", "ACDEFGHIKM"}))
+	if len(r.Issues) != 0 || r.Type != "protein" {
+		t.Fatalf("clean protein got %+v", r)
+	}
+}
@@ -10,8 +10,8 @@ import (
 )
 
 // MyMain is the top level main, after parsing the command line.
-func MyMain(seqstring, infile, outfile string) int {
-	s_opts := &seq.Options{}
+// s_opts may set the input and output formats.
+func MyMain(seqstring, infile, outfile string, s_opts *seq.Options) int {
 
 	seqgrp, err := seq.Readfile(infile, s_opts)
 	if err != nil {
@@ -23,34 +23,21 @@ func MyMain(seqstring, infile, outfile string) int {
 		fmt.Fprintf(os.Stderr, `Could not find "%s" amongst sequences\n`, seqstring)
 		return ExitFailure
 	}
-	seqslc := seqgrp.SeqSlc()       // Slice of sequences
-	maskseq := seqslc[ndxref].GetSeq() // The reference sequence
-	nfullseq := len(maskseq)
-	mask := make([]bool, nfullseq)
-	for i := range mask { // First, we assume all sites are interesting
-		mask[i] = true
-	}
-	for i, c := range maskseq { // Then we pick out the few that
-		if c == GapChar { //       should be taken out.
-			mask[i] = false
-		}
+	maskseq := seqgrp.SeqSlc()[ndxref].GetSeq() // The reference sequence
+	mask := make([]bool, len(maskseq))
+	for i, c := range maskseq { // Keep the sites where
+		mask[i] = c != GapChar //  the reference has a residue
 	}
 	const emsg = "Length mismatch ref: %d seq %d len %d\n"
 	for i, ss := range seqgrp.SeqSlc() {
-		if len(ss.GetSeq()) != nfullseq {
-			fmt.Fprintf(os.Stderr, emsg, nfullseq, i, len(ss.GetSeq()))
+		if len(ss.GetSeq()) != len(mask) {
+			fmt.Fprintf(os.Stderr, emsg, len(mask), i, len(ss.GetSeq()))
 			return ExitFailure
 		}
-		b := ss.GetSeq()[:0]
-		for i, c := range ss.GetSeq() {
-			if mask[i] {
-				b = append(b, c)
-			}
-		}
-		seqgrp.SeqSlc()[i].SetSeq(b) // do not use "ss" here
 	}
+	seqgrp.KeepCols(mask)
 
-	if err := seq.WriteToF(outfile, seqgrp.SeqSlc(), s_opts); err != nil {
+	if err := seq.Writefile(outfile, seqgrp, s_opts); err != nil {
 		if outfile == "" {
 			outfile = "os.Stdout"
 		}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"testing"
 
+	"github.com/andrew-torda/seq_compat/pkg/seq"
 	"github.com/andrew-torda/seq_compat/pkg/seq/common"
 	. "github.com/andrew-torda/seq_compat/pkg/squash"
 )
@@ -26,7 +27,7 @@ func ExampleMain () {
 		log.Fatal(err)
 	}
 	defer os.Remove(fname)
-	if MyMain("s2", fname, "") != common.ExitSuccess {
+	if MyMain("s2", fname, "", &seq.Options{}) != common.ExitSuccess {
 		log.Fatal("broke running squash main")
 	}
 // Output:
@@ -50,7 +51,7 @@ func TestWithOutput (t *testing.T) {
 	if (err != nil) {
 		t.Fatal("cannot make temp filename", err)
 	}
-	r := MyMain("s2", fname, outfname)
+	r := MyMain("s2", fname, outfname, &seq.Options{})
 	defer os.Remove(outfname)
 	if r != common.ExitSuccess {
 		t.Fatal("broke running squash main")
@@ -86,7 +87,7 @@ ABCD
 	old := os.Stderr // We provoke an error, so temporarily redirect stderr.
 	os.Stderr, err = os.OpenFile(os.DevNull, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if (err != nil) {t.Fatal("fail opening", os.DevNull)}
-	if MyMain("s2", fname, "") != common.ExitFailure {
+	if MyMain("s2", fname, "", &seq.Options{}) != common.ExitFailure {
 		os.Stderr = old
 		t.Fatal("broke running squash main")
 	}
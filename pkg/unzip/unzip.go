@@ -0,0 +1,102 @@
+// 17 Oct 2026
+
+// Package unzip recognises gzip and bzip2 compressed input by its first
+// few bytes and hands back a reader for the decompressed contents.
+// It is used wherever we read sequences, so nobody has to decompress
+// to a temporary file first.
+package unzip
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// Kind says what sort of compression we have.
+type Kind byte
+
+const (
+	None  Kind = iota // Not compressed, or nothing we recognise
+	Gzip              // gzip, including bgzip and concatenated members
+	Bzip2             // bzip2
+)
+
+// MagicLen is the number of bytes Sniff needs to see.
+const MagicLen = 3
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+// Sniff looks at the first bytes of some input and says how it is
+// compressed.
+func Sniff(head []byte) Kind {
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(head, bzip2Magic):
+		return Bzip2
+	}
+	return None
+}
+
+// NewReader returns a reader which decompresses rdr. If kind is None,
+// it just returns rdr.
+func NewReader(rdr io.Reader, kind Kind) (io.Reader, error) {
+	switch kind {
+	case Gzip:
+		return gzip.NewReader(rdr)
+	case Bzip2:
+		return bzip2.NewReader(rdr), nil
+	}
+	return rdr, nil
+}
+
+// Stream looks at the start of a reader which may not be able to seek,
+// such as a pipe, and returns a reader for the decompressed contents.
+func Stream(rdr io.Reader) (io.Reader, Kind, error) {
+	br := bufio.NewReader(rdr)
+	head, err := br.Peek(MagicLen)
+	if err != nil && err != io.EOF {
+		return nil, None, err
+	}
+	kind := Sniff(head)
+	zr, err := NewReader(br, kind)
+	return zr, kind, err
+}
+
+// readCloser lets us close the file underneath a decompressor.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Open opens a file and returns a reader for its decompressed contents,
+// as well as the kind of compression that was found. If the file is not
+// compressed, the reader is the *os.File.
+func Open(fname string) (io.ReadCloser, Kind, error) {
+	fp, err := os.Open(fname)
+	if err != nil {
+		return nil, None, err
+	}
+	var head [MagicLen]byte
+	n, _ := io.ReadFull(fp, head[:])
+	if _, err := fp.Seek(0, io.SeekStart); err != nil {
+		fp.Close()
+		return nil, None, err
+	}
+	kind := Sniff(head[:n])
+	if kind == None {
+		return fp, None, nil
+	}
+	zr, err := NewReader(fp, kind)
+	if err != nil {
+		fp.Close()
+		return nil, None, err
+	}
+	return readCloser{zr, fp}, kind, nil
+}
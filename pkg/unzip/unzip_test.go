@@ -0,0 +1,71 @@
+// 17 Oct 2026
+
+package unzip_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/andrew-torda/seq_compat/pkg/unzip"
+)
+
+const text = "> s1\nACDEFGHIKL\n> s2\nACDEFGHIKL\n"
+
+// bzip2 of text, made with the bzip2 program, since the library
+// cannot compress.
+var bz2Text = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x28, 0x1c,
+	0x6f, 0x31, 0x00, 0x00, 0x04, 0x5c, 0x80, 0x00, 0x10, 0x40, 0x00, 0x30,
+	0x01, 0x2f, 0xec, 0x08, 0x00, 0x20, 0x00, 0x31, 0x4c, 0x00, 0x13, 0x40,
+	0xaa, 0x93, 0x4f, 0x28, 0x31, 0x36, 0xa6, 0xd1, 0x12, 0xa4, 0x9d, 0xbc,
+	0x7a, 0xc3, 0x2d, 0x3e, 0x6d, 0x4a, 0x7e, 0x2e, 0xe4, 0x8a, 0x70, 0xa1,
+	0x20, 0x50, 0x38, 0xde, 0x62,
+}
+
+func gz(s string) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write([]byte(s))
+	zw.Close()
+	return b.Bytes()
+}
+
+// TestOpen writes plain, gzip and bzip2 versions of a file and checks
+// we get the same text back from each.
+func TestOpen(t *testing.T) {
+	data := []struct {
+		b    []byte
+		kind unzip.Kind
+	}{
+		{[]byte(text), unzip.None},
+		{gz(text), unzip.Gzip},
+		{bz2Text, unzip.Bzip2},
+	}
+	for _, d := range data {
+		fp, err := os.CreateTemp("", "_del_me_testing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(fp.Name())
+		fp.Write(d.b)
+		fp.Close()
+		rdr, kind, err := unzip.Open(fp.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind != d.kind {
+			t.Fatal("wanted kind", d.kind, "got", kind)
+		}
+		got, err := io.ReadAll(rdr)
+		rdr.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != text {
+			t.Fatalf("kind %d got \"%s\"", kind, got)
+		}
+	}
+}